@@ -0,0 +1,521 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/the-control-group/go-jsonpath"
+)
+
+// Condition is the left hand side of a filter under construction
+type Condition struct {
+	path jsonpath.JsonPath
+}
+
+// Expr is a filter under construction. Exprs are immutable, combining them
+// always returns a new Expr.
+type Expr struct {
+	f *Filter
+}
+
+// Path starts a filter comparing the value at path. It panics if path is not
+// a valid JSONPath.
+func Path(path string) Condition {
+	return Condition{path: jsonpath.MustParsePath(path)}
+}
+
+// Script builds a filter evaluating a script with the given interpreter
+func Script(interpreter string, script string) Expr {
+	return Expr{&Filter{Script: &ScriptFilter{Interpreter: interpreter, Script: script}}}
+}
+
+func (c Condition) compare(operator string, value interface{}) Expr {
+	return Expr{&Filter{Path: c.path, Operator: operator, Value: builderValue(value)}}
+}
+
+// Eq passes when the value equals v
+func (c Condition) Eq(v interface{}) Expr {
+	return c.compare(opEqual, v)
+}
+
+// Ne passes when the value does not equal v
+func (c Condition) Ne(v interface{}) Expr {
+	return c.compare(opNotEqual, v)
+}
+
+// In passes when the value equals one of values
+func (c Condition) In(values ...interface{}) Expr {
+	return c.compare(opIn, values)
+}
+
+// NotIn passes when the value equals none of values
+func (c Condition) NotIn(values ...interface{}) Expr {
+	return c.compare(opNotIn, values)
+}
+
+// InRef passes when the value equals an element of the array at path
+func (c Condition) InRef(path string) Expr {
+	return c.compare(opIn, Ref(path))
+}
+
+// NotInRef passes when the value equals no element of the array at path
+func (c Condition) NotInRef(path string) Expr {
+	return c.compare(opNotIn, Ref(path))
+}
+
+// Lt passes when the value is less than v
+func (c Condition) Lt(v interface{}) Expr {
+	return c.compare(opLessThan, v)
+}
+
+// Gt passes when the value is greater than v
+func (c Condition) Gt(v interface{}) Expr {
+	return c.compare(opGreaterThan, v)
+}
+
+// Gte passes when the value is greater than or equal to v
+func (c Condition) Gte(v interface{}) Expr {
+	return c.compare(opGreaterEqual, v)
+}
+
+// Lte passes when the value is less than or equal to v
+func (c Condition) Lte(v interface{}) Expr {
+	return c.compare(opLessEqual, v)
+}
+
+// OlderThan passes when the timestamp value is more than d in the past
+func (c Condition) OlderThan(d time.Duration) Expr {
+	return c.compare(opOlderThan, durationString(d))
+}
+
+// NewerThan passes when the timestamp value is less than d in the past
+func (c Condition) NewerThan(d time.Duration) Expr {
+	return c.compare(opNewerThan, durationString(d))
+}
+
+// Before passes when the timestamp value is before t
+func (c Condition) Before(t time.Time) Expr {
+	return c.compare(opBefore, t.Format(time.RFC3339Nano))
+}
+
+// After passes when the timestamp value is after t
+func (c Condition) After(t time.Time) Expr {
+	return c.compare(opAfter, t.Format(time.RFC3339Nano))
+}
+
+// BeforeRef passes when the timestamp value is before the timestamp at path
+func (c Condition) BeforeRef(path string) Expr {
+	return c.compare(opBefore, Ref(path))
+}
+
+// AfterRef passes when the timestamp value is after the timestamp at path
+func (c Condition) AfterRef(path string) Expr {
+	return c.compare(opAfter, Ref(path))
+}
+
+// WithinLast passes when the timestamp value is in the past by at most d
+func (c Condition) WithinLast(d time.Duration) Expr {
+	return c.compare(opWithinLast, durationString(d))
+}
+
+// WithinNext passes when the timestamp value is in the future by at most d
+func (c Condition) WithinNext(d time.Duration) Expr {
+	return c.compare(opWithinNext, durationString(d))
+}
+
+// RegexMatch passes when the value matches pattern
+func (c Condition) RegexMatch(pattern string) Expr {
+	return c.compare(opRegexMatch, pattern)
+}
+
+// RegexNoMatch passes when the value does not match pattern
+func (c Condition) RegexNoMatch(pattern string) Expr {
+	return c.compare(opRegexNoMatch, pattern)
+}
+
+// Contains passes when the string value contains sub
+func (c Condition) Contains(sub string) Expr {
+	return c.compare(opContains, sub)
+}
+
+// NotContains passes when the string value does not contain sub
+func (c Condition) NotContains(sub string) Expr {
+	return c.compare(opNotContains, sub)
+}
+
+// StartsWith passes when the string value starts with prefix
+func (c Condition) StartsWith(prefix string) Expr {
+	return c.compare(opStartsWith, prefix)
+}
+
+// EndsWith passes when the string value ends with suffix
+func (c Condition) EndsWith(suffix string) Expr {
+	return c.compare(opEndsWith, suffix)
+}
+
+// EqualsIgnoreCase passes when the string value equals s under Unicode case
+// folding
+func (c Condition) EqualsIgnoreCase(s string) Expr {
+	return c.compare(opEqualsIgnoreCase, s)
+}
+
+// ContainsIgnoreCase is Contains under Unicode case folding
+func (c Condition) ContainsIgnoreCase(sub string) Expr {
+	return c.compare(opContainsIgnoreCase, sub)
+}
+
+// NotContainsIgnoreCase is NotContains under Unicode case folding
+func (c Condition) NotContainsIgnoreCase(sub string) Expr {
+	return c.compare(opNotContainsIgnoreCase, sub)
+}
+
+// StartsWithIgnoreCase is StartsWith under Unicode case folding
+func (c Condition) StartsWithIgnoreCase(prefix string) Expr {
+	return c.compare(opStartsWithIgnoreCase, prefix)
+}
+
+// EndsWithIgnoreCase is EndsWith under Unicode case folding
+func (c Condition) EndsWithIgnoreCase(suffix string) Expr {
+	return c.compare(opEndsWithIgnoreCase, suffix)
+}
+
+// Glob passes when the string value matches the glob pattern
+func (c Condition) Glob(pattern string) Expr {
+	return c.compare(opGlob, pattern)
+}
+
+// NotGlob passes when the string value does not match the glob pattern
+func (c Condition) NotGlob(pattern string) Expr {
+	return c.compare(opNotGlob, pattern)
+}
+
+// GlobIgnoreCase is Glob ignoring case
+func (c Condition) GlobIgnoreCase(pattern string) Expr {
+	return c.compare(opGlobIgnoreCase, pattern)
+}
+
+// NotGlobIgnoreCase is NotGlob ignoring case
+func (c Condition) NotGlobIgnoreCase(pattern string) Expr {
+	return c.compare(opNotGlobIgnoreCase, pattern)
+}
+
+// Between passes when the value is at least min and at most max
+func (c Condition) Between(min, max interface{}) Expr {
+	return c.compare(opBetween, []interface{}{min, max})
+}
+
+// BetweenExclusive passes when the value is at least min and less than max
+func (c Condition) BetweenExclusive(min, max interface{}) Expr {
+	return c.compare(opBetween, map[string]interface{}{"min": builderValue(min), "max": builderValue(max), "maxInclusive": false})
+}
+
+// NotBetween passes when the value is less than min or greater than max
+func (c Condition) NotBetween(min, max interface{}) Expr {
+	return c.compare(opNotBetween, []interface{}{min, max})
+}
+
+// AnyElement passes when the value is an array and e passes for at least one
+// of its elements. Paths in e are relative to the element.
+func (c Condition) AnyElement(e Expr) Expr {
+	return c.compare(opAnyElement, e.Filter())
+}
+
+// AllElements passes when the value is an array and e passes for every one
+// of its elements
+func (c Condition) AllElements(e Expr) Expr {
+	return c.compare(opAllElements, e.Filter())
+}
+
+// NoElement passes when the value is an array and e passes for none of its
+// elements
+func (c Condition) NoElement(e Expr) Expr {
+	return c.compare(opNoElement, e.Filter())
+}
+
+// CountElements passes when the number of elements of the array e passes
+// for compares with n using operator, one of eq, ne, lt, gt, gte, lte,
+// between and notBetween. It panics for other operators.
+func (c Condition) CountElements(e Expr, operator string, n interface{}) Expr {
+	if !isCountComparison(operator) {
+		panic(fmt.Sprintf("countElements does not support operator %q", operator))
+	}
+	op, _ := canonicalOperator(operator)
+	return c.compare(opCountElements, &elementCount{Filter: e.Filter(), Operator: op, Value: builderValue(n)})
+}
+
+// ContainsAll passes when the array value contains every one of values
+func (c Condition) ContainsAll(values ...interface{}) Expr {
+	return c.compare(opContainsAll, values)
+}
+
+// ContainsAny passes when the array value contains at least one of values
+func (c Condition) ContainsAny(values ...interface{}) Expr {
+	return c.compare(opContainsAny, values)
+}
+
+// ContainsNone passes when the array value contains none of values
+func (c Condition) ContainsNone(values ...interface{}) Expr {
+	return c.compare(opContainsNone, values)
+}
+
+// SubsetOf passes when every element of the array value is one of values
+func (c Condition) SubsetOf(values ...interface{}) Expr {
+	return c.compare(opSubsetOf, values)
+}
+
+// EqualSet passes when the array value and values contain the same elements,
+// ignoring order and repetition
+func (c Condition) EqualSet(values ...interface{}) Expr {
+	return c.compare(opEqualSet, values)
+}
+
+// LengthEq passes when the string, array or object value has length n.
+// Strings are measured in runes.
+func (c Condition) LengthEq(n int) Expr {
+	return c.compare(opLengthEq, n)
+}
+
+// LengthNe passes when the value does not have length n
+func (c Condition) LengthNe(n int) Expr {
+	return c.compare(opLengthNe, n)
+}
+
+// LengthLt passes when the length of the value is less than n
+func (c Condition) LengthLt(n int) Expr {
+	return c.compare(opLengthLt, n)
+}
+
+// LengthLte passes when the length of the value is at most n
+func (c Condition) LengthLte(n int) Expr {
+	return c.compare(opLengthLte, n)
+}
+
+// LengthGt passes when the length of the value is greater than n
+func (c Condition) LengthGt(n int) Expr {
+	return c.compare(opLengthGt, n)
+}
+
+// LengthGte passes when the length of the value is at least n
+func (c Condition) LengthGte(n int) Expr {
+	return c.compare(opLengthGte, n)
+}
+
+// IsEmpty passes when the value is null or an empty string, array or object
+func (c Condition) IsEmpty() Expr {
+	return c.compare(opIsEmpty, nil)
+}
+
+// IsNotEmpty passes when the value is a non-empty string, array or object
+func (c Condition) IsNotEmpty() Expr {
+	return c.compare(opIsNotEmpty, nil)
+}
+
+// IsType passes when the value is of one of the JSON types named: string,
+// number, integer, boolean, array, object or null
+func (c Condition) IsType(types ...string) Expr {
+	values := make([]interface{}, len(types))
+	for i, t := range types {
+		values[i] = t
+	}
+	return c.compare(opIsType, values)
+}
+
+// IsTimestamp passes when the value is a string olderThan can parse
+func (c Condition) IsTimestamp() Expr {
+	return c.compare(opIsTimestamp, nil)
+}
+
+// IsNumeric passes when the value is a number or a string holding one
+func (c Condition) IsNumeric() Expr {
+	return c.compare(opIsNumeric, nil)
+}
+
+// Exists passes when the path resolves, even to null
+func (c Condition) Exists() Expr {
+	return c.compare(opExists, nil)
+}
+
+// NotExists passes when the path does not resolve
+func (c Condition) NotExists() Expr {
+	return c.compare(opNotExists, nil)
+}
+
+// IsNull passes when the path resolves to null
+func (c Condition) IsNull() Expr {
+	return c.compare(opIsNull, nil)
+}
+
+// IsNotNull passes when the path resolves to a value other than null
+func (c Condition) IsNotNull() Expr {
+	return c.compare(opIsNotNull, nil)
+}
+
+// And returns an Expr passing when e and all of others pass
+func (e Expr) And(others ...Expr) Expr {
+	f := e.f
+	for _, o := range others {
+		f = and(f, o.f)
+	}
+	return Expr{f}
+}
+
+// Or returns an Expr passing when e or any of others pass. Each of others is
+// copied into every link of the And chain of e, so or-ing several long And
+// chains grows the filter multiplicatively.
+func (e Expr) Or(others ...Expr) Expr {
+	f := e.f
+	for _, o := range others {
+		f = or(f, o.f)
+	}
+	return Expr{f}
+}
+
+// Requeue returns e with Requeue set on its root filter
+func (e Expr) Requeue() Expr {
+	f := *e.f
+	f.Requeue = true
+	return Expr{&f}
+}
+
+// OnMissing returns e with OnMissing set to mode on its root filter
+func (e Expr) OnMissing(mode string) Expr {
+	f := *e.f
+	f.OnMissing = mode
+	return Expr{&f}
+}
+
+// Filter returns a copy of the constructed filter
+func (e Expr) Filter() *Filter {
+	return cloneFilter(e.f)
+}
+
+// Test evaluates the constructed filter
+func (e Expr) Test(msg interface{}) (bool, error) {
+	return e.f.Test(msg)
+}
+
+// MarshalJSON implements json.Marshaler
+func (e Expr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.f)
+}
+
+// and returns x && y. Test evaluates a node as (f || f.Or) && f.And, so y is
+// appended to the end of the And chain.
+func and(x, y *Filter) *Filter {
+	c := *x
+	if c.And == nil {
+		c.And = y
+	} else {
+		c.And = and(c.And, y)
+	}
+	return &c
+}
+
+// or returns x || y. When x has an And clause the disjunction is distributed:
+// (x0 || xo) && xa || y is (x0 || xo || y) && (xa || y). The tree has no
+// grouping node, so y is copied once per link of the And chain: a && b && c
+// || d holds three copies of d, and or-ing such terms together multiplies
+// the copies.
+func or(x, y *Filter) *Filter {
+	c := *x
+	if c.Or == nil {
+		c.Or = y
+	} else {
+		c.Or = or(c.Or, y)
+	}
+	if c.And != nil {
+		c.And = or(c.And, y)
+	}
+	return &c
+}
+
+func cloneFilter(f *Filter) *Filter {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	c.Or = cloneFilter(f.Or)
+	c.And = cloneFilter(f.And)
+	if f.Script != nil {
+		s := *f.Script
+		c.Script = &s
+	}
+	switch v := f.Value.(type) {
+	case *Filter:
+		c.Value = cloneFilter(v)
+	case *elementCount:
+		count := *v
+		count.Filter = cloneFilter(v.Filter)
+		c.Value = &count
+	case *PathRef:
+		ref := *v
+		c.Value = &ref
+	}
+	return &c
+}
+
+// builderValue converts Go numbers to json.Number so built filters compare
+// exactly like decoded ones
+func builderValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case int8:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case int16:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case int32:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case uint:
+		return json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		return json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		return json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		return json.Number(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		return json.Number(strconv.FormatUint(v, 10))
+	case float32:
+		return json.Number(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64))
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = builderValue(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// durationString formats d in the largest whole unit ParseApproxBigDuration
+// reads back exactly. Sub-second precision is truncated.
+func durationString(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	d = d.Truncate(time.Second)
+	units := []struct {
+		d    time.Duration
+		unit string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+	}
+	for _, u := range units {
+		if d != 0 && d%u.d == 0 {
+			return fmt.Sprintf("%s%d%s", sign, d/u.d, u.unit)
+		}
+	}
+	return fmt.Sprintf("%s%ds", sign, d/time.Second)
+}
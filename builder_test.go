@@ -0,0 +1,295 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBuilderToJSON(t *testing.T) {
+	expr := Path("$.amount").Gt(100).And(Path("$.country").In("US", "CA"))
+	b, err := json.Marshal(expr)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"template":null,"path":"$.amount","value":100,"operator":"gt","requeue":false,"or":null,"and":{"template":null,"path":"$.country","value":["US","CA"],"operator":"in","requeue":false,"or":null,"and":null,"script":null},"script":null}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+	filter, err := ParseJSON(b)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var msg1 interface{}
+	msg1, err = decodeJSONMessage([]byte(`{"amount":101,"country":"CA"}`))
+	if err != nil {
+		t.Error("Failed to parse message 1", err)
+		return
+	}
+	var pass bool
+	pass, err = filter.Test(msg1)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("101 in CA should pass")
+		return
+	}
+}
+
+func TestBuilderAndOr(t *testing.T) {
+	// (a && b) || c must not require b when c passes
+	expr := Path("$.a").Eq(1).And(Path("$.b").Eq(1)).Or(Path("$.c").Eq(1))
+	cases := []struct {
+		msg  string
+		pass bool
+	}{
+		{`{"a":1,"b":1,"c":0}`, true},
+		{`{"a":1,"b":0,"c":0}`, false},
+		{`{"a":0,"b":0,"c":1}`, true},
+		{`{"a":1,"b":0,"c":1}`, true},
+		{`{"a":0,"b":1,"c":0}`, false},
+	}
+	for _, c := range cases {
+		msg, err := decodeJSONMessage([]byte(c.msg))
+		if err != nil {
+			t.Error("Failed to parse message", err)
+			return
+		}
+		pass, err := expr.Test(msg)
+		if err != nil {
+			t.Error("Filter test failed", err)
+			return
+		}
+		if pass != c.pass {
+			t.Errorf("%s should pass: %t", c.msg, c.pass)
+		}
+	}
+}
+
+func TestBuilderOlderThan(t *testing.T) {
+	filter := Path("$.value").OlderThan(5 * time.Minute).Filter()
+	if filter.Value != "5m" {
+		t.Errorf("duration should be 5m, got %v", filter.Value)
+		return
+	}
+	var msg1 interface{}
+	msg1, err := decodeJSONMessage([]byte(`{"value":"` + time.Now().Add(-6*time.Minute).Format(time.RFC3339) + `"}`))
+	if err != nil {
+		t.Error("Failed to parse message 1", err)
+		return
+	}
+	var pass bool
+	pass, err = filter.Test(msg1)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("6m ago should pass")
+		return
+	}
+}
+
+func TestBuilderExistence(t *testing.T) {
+	expr := Path("$.deleted").NotExists().Or(Path("$.deleted").IsNull()).And(Path("$.amount").Gt(100).OnMissing(MissingError))
+	b, err := json.Marshal(expr)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"template":null,"path":"$.deleted","value":null,"operator":"notExists","requeue":false,"or":{"template":null,"path":"$.deleted","value":null,"operator":"isNull","requeue":false,"or":null,"and":null,"script":null},"and":{"template":null,"path":"$.amount","value":100,"operator":"gt","requeue":false,"onMissing":"error","or":null,"and":null,"script":null},"script":null}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+	pass, err := expr.Test(map[string]interface{}{"deleted": nil, "amount": 150.0})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("null deleted should pass")
+	}
+	_, err = expr.Test(map[string]interface{}{})
+	if err == nil {
+		t.Error("missing amount should fail with an error")
+	}
+}
+
+func TestBuilderStrings(t *testing.T) {
+	expr := Path("$.host").StartsWithIgnoreCase("api-").And(Path("$.message").NotContains("healthcheck"))
+	pass, err := expr.Test(map[string]interface{}{"host": "API-1", "message": "GET /orders"})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("API-1 should pass")
+	}
+	pass, err = expr.Test(map[string]interface{}{"host": "API-1", "message": "GET /healthcheck"})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if pass {
+		t.Error("healthcheck should not pass")
+	}
+}
+
+func TestBuilderGlob(t *testing.T) {
+	expr := Path("$.path").Glob("/v1/users/*/orders").And(Path("$.host").NotGlobIgnoreCase("*.internal"))
+	pass, err := expr.Test(map[string]interface{}{"path": "/v1/users/7/orders", "host": "api.example.com"})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("orders of user 7 should pass")
+	}
+}
+
+func TestBuilderBetween(t *testing.T) {
+	expr := Path("$.price").BetweenExclusive(10, 20).And(Path("$.qty").NotBetween(0, 0))
+	for _, c := range []struct {
+		price float64
+		pass  bool
+	}{{10, true}, {19.5, true}, {20, false}} {
+		pass, err := expr.Test(map[string]interface{}{"price": c.price, "qty": 1.0})
+		if err != nil {
+			t.Error("Filter test failed", err)
+			return
+		}
+		if pass != c.pass {
+			t.Errorf("%v should pass: %t", c.price, c.pass)
+		}
+	}
+}
+
+func TestBuilderElements(t *testing.T) {
+	line := Path("$.left").Eq("BILLED_TO").And(Path("$.link").Eq("CreditCard"), Path("$.overusers").Gt(0))
+	expr := Path("$.network").AnyElement(line).And(Path("$.network").CountElements(Path("$.total").Gte(1), ">=", 1))
+	msg := map[string]interface{}{"network": []interface{}{
+		map[string]interface{}{"left": "BILLED_TO", "link": "CreditCard", "overusers": 1.0, "total": 2.0},
+	}}
+	pass, err := expr.Test(msg)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("billed network should pass")
+	}
+	b, err := json.Marshal(expr)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	filter, err := ParseJSON(b)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	pass, err = filter.Test(msg)
+	if err != nil || !pass {
+		t.Error("decoded filter should pass", err)
+	}
+}
+
+func TestBuilderSets(t *testing.T) {
+	expr := Path("$.tags").ContainsAny("vip", "beta").And(Path("$.ids").SubsetOf(1, 2, 3))
+	pass, err := expr.Test(map[string]interface{}{"tags": []interface{}{"beta"}, "ids": []interface{}{3, int64(1)}})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("beta with ids 1 and 3 should pass")
+	}
+}
+
+func TestBuilderLength(t *testing.T) {
+	expr := Path("$.items").IsNotEmpty().And(Path("$.comment").LengthLt(500))
+	pass, err := expr.Test(map[string]interface{}{"items": []interface{}{1}, "comment": "ok"})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("one item with a short comment should pass")
+	}
+	pass, err = expr.Test(map[string]interface{}{"items": []interface{}{}, "comment": "ok"})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if pass {
+		t.Error("no items should not pass")
+	}
+}
+
+func TestBuilderTypes(t *testing.T) {
+	// route malformed events before the main rules
+	malformed := Path("$.amount").IsNumeric().And(Path("$.created").IsTimestamp(), Path("$.tags").IsType("array", "null"))
+	pass, err := malformed.Test(map[string]interface{}{"amount": "12.50", "created": "2024-03-15T10:00:00Z", "tags": nil})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("well formed event should pass")
+	}
+}
+
+func TestBuilderRef(t *testing.T) {
+	overrun := Path("$.spent").Gt(Ref("$.budget")).And(Path("$.region").InRef("$.regions"))
+	pass, err := overrun.Test(map[string]interface{}{"spent": 120, "budget": 100, "region": "eu", "regions": []interface{}{"eu", "us"}})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("spent over budget should pass")
+	}
+	b, err := json.Marshal(overrun.Filter())
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	f, err := ParseJSON(b)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	if _, ok := f.Value.(*PathRef); !ok {
+		t.Errorf("expected value to decode as a reference, got %T", f.Value)
+	}
+}
+
+func TestBuilderTimes(t *testing.T) {
+	now := time.Now()
+	promo := Path("$.starts").Before(now).And(Path("$.ends").AfterRef("$.starts"), Path("$.expires").WithinNext(7*24*time.Hour))
+	pass, err := promo.Test(map[string]interface{}{
+		"starts":  now.Add(-time.Hour).Format(time.RFC3339),
+		"ends":    now.Add(time.Hour).Format(time.RFC3339),
+		"expires": now.Add(72 * time.Hour).Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("running promotion should pass")
+	}
+	b, err := json.Marshal(Path("$.created").WithinLast(30 * time.Minute).Or(Path("$.created").After(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))))
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"template":null,"path":"$.created","value":"30m","operator":"withinLast","requeue":false,"or":{"template":null,"path":"$.created","value":"2024-03-01T00:00:00Z","operator":"after","requeue":false,"or":null,"and":null,"script":null},"and":null,"script":null}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	filter "github.com/nickcarenza/go-filter"
+)
+
+func runDiff(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gofilter diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	corpus := fs.String("corpus", "", "also list the messages in `file` (JSON, JSON array or JSON Lines) whose outcome differs")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: gofilter diff [-corpus file] old new\n")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError
+	}
+	old, err := filter.ParseFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, "gofilter:", err)
+		return exitError
+	}
+	new, err := filter.ParseFile(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stderr, "gofilter:", err)
+		return exitError
+	}
+	code := exitMatch
+	for _, c := range filter.Diff(old, new) {
+		fmt.Fprintln(stdout, c)
+		code = exitNoMatch
+	}
+	if *corpus == "" {
+		return code
+	}
+	samples, err := readSamples(*corpus)
+	if err != nil {
+		fmt.Fprintln(stderr, "gofilter:", err)
+		return exitError
+	}
+	flipped := 0
+	for i, s := range samples {
+		before, after := outcome(old, s), outcome(new, s)
+		if before != after {
+			fmt.Fprintf(stdout, "record %d: %s -> %s: %s\n", i+1, before, after, replValue(s, 0))
+			flipped++
+		}
+	}
+	fmt.Fprintf(stdout, "%d of %d records changed outcome\n", flipped, len(samples))
+	if flipped > 0 {
+		code = exitNoMatch
+	}
+	return code
+}
+
+// outcome evaluates f against msg as pass, fail or error
+func outcome(f *filter.Filter, msg interface{}) string {
+	trace, err := f.Explain(msg)
+	switch {
+	case err != nil:
+		return "error"
+	case trace.Result:
+		return "pass"
+	}
+	return "fail"
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.json")
+	new := filepath.Join(dir, "new.yaml")
+	same := filepath.Join(dir, "same.json")
+	corpus := filepath.Join(dir, "events.jsonl")
+	for name, content := range map[string]string{
+		old:    `{"path":"$.status","operator":">=","value":500}`,
+		new:    "path: $.status\noperator: gt\nvalue: 500\n",
+		same:   `{"path":"$.status","operator":"greater than or equal to","value":500}`,
+		corpus: events,
+	} {
+		err := os.WriteFile(name, []byte(content), 0644)
+		if err != nil {
+			t.Error("Failed to write file", err)
+			return
+		}
+	}
+
+	code, out, errOut := runCommand([]string{"diff", old, same}, "")
+	if code != exitMatch || out != "" {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCommand([]string{"diff", old, new}, "")
+	if code != exitNoMatch || out != "modified # operator: \"gte\" -> \"gt\"\n" {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCommand([]string{"diff", "-corpus", corpus, old, same}, "")
+	if code != exitMatch || out != "0 of 3 records changed outcome\n" {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCommand([]string{"diff", "-corpus", corpus, old, new}, "")
+	expected := "modified # operator: \"gte\" -> \"gt\"\n" +
+		"record 3: pass -> fail: {\"path\":\"/health\",\"status\":500}\n" +
+		"1 of 3 records changed outcome\n"
+	if code != exitNoMatch || out != expected {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCommand([]string{"diff", old}, "")
+	if code != exitError {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+}
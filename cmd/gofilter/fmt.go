@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	filter "github.com/nickcarenza/go-filter"
+)
+
+func runFmt(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gofilter fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	check := fs.Bool("check", false, "list the files which are not formatted and exit with 1 if there are any")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: gofilter fmt [-w | -check] [dir | filter.json ...]\n")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		if *write || *check {
+			fmt.Fprintln(stderr, "gofilter: -w and -check need files")
+			return exitError
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "gofilter:", err)
+			return exitError
+		}
+		formatted, err := formatFilter(data)
+		if err != nil {
+			fmt.Fprintln(stderr, "gofilter:", err)
+			return exitError
+		}
+		stdout.Write(formatted)
+		return exitMatch
+	}
+	files, err := filterFiles(fs.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, "gofilter:", err)
+		return exitError
+	}
+	code := exitMatch
+	for _, name := range files {
+		if strings.ToLower(filepath.Ext(name)) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(name)
+		if err == nil {
+			var formatted []byte
+			formatted, err = formatFilter(data)
+			switch {
+			case err != nil:
+			case *check:
+				if !bytes.Equal(data, formatted) {
+					fmt.Fprintln(stdout, name)
+					code = exitNoMatch
+				}
+			case *write:
+				if !bytes.Equal(data, formatted) {
+					err = os.WriteFile(name, formatted, 0644)
+				}
+			default:
+				_, err = stdout.Write(formatted)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "gofilter: %s: %s\n", name, err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+// formatFilter formats a filter document, refusing documents with fields a
+// filter does not have so other JSON files are not rewritten
+func formatFilter(data []byte) ([]byte, error) {
+	var doc interface{}
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("not a filter document")
+	}
+	err = checkFields(doc, reflect.TypeOf(filter.Filter{}))
+	if err != nil {
+		return nil, err
+	}
+	f, err := filter.ParseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Format(f)
+}
+
+// checkFields reports the first field of the object doc which the struct t
+// does not have, checking or, and and script clauses too. ParseJSON ignores
+// unknown fields.
+func checkFields(doc interface{}, t reflect.Type) error {
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[name] = t.Field(i).Type
+	}
+	for k, v := range m {
+		ft, ok := fields[k]
+		if !ok {
+			return fmt.Errorf("not a filter document: unknown field %q", k)
+		}
+		switch ft {
+		case reflect.TypeOf(&filter.Filter{}), reflect.TypeOf(&filter.ScriptFilter{}):
+			err := checkFields(v, ft.Elem())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const formatted = `{
+  "path": "$.status",
+  "value": 500,
+  "operator": "gte"
+}
+`
+
+func TestRunFmt(t *testing.T) {
+	code, out, errOut := runCommand([]string{"fmt"}, `{"operator":">=","path":"$.status","value":500,"or":null}`)
+	if code != exitMatch || out != formatted {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+
+	dir := t.TempDir()
+	messy := filepath.Join(dir, "messy.json")
+	clean := filepath.Join(dir, "clean.json")
+	for name, content := range map[string]string{
+		messy:                             `{"operator":">=","path":"$.status","value":500}`,
+		clean:                             formatted,
+		filepath.Join(dir, "rule.yaml"):   "path: $.status\n",
+		filepath.Join(dir, "a.test.json"): `{"cases":[]}`,
+	} {
+		err := os.WriteFile(name, []byte(content), 0644)
+		if err != nil {
+			t.Error("Failed to write file", err)
+			return
+		}
+	}
+	code, out, errOut = runCommand([]string{"fmt", "-check", dir}, "")
+	if code != exitNoMatch || out != messy+"\n" {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+	code, _, errOut = runCommand([]string{"fmt", "-w", dir}, "")
+	if code != exitMatch {
+		t.Errorf("Unexpected result %d %q", code, errOut)
+	}
+	data, err := os.ReadFile(messy)
+	if err != nil || string(data) != formatted {
+		t.Errorf("File was not formatted %q", data)
+	}
+	code, out, _ = runCommand([]string{"fmt", "-check", dir}, "")
+	if code != exitMatch || out != "" {
+		t.Errorf("Unexpected result %d %q", code, out)
+	}
+	code, _, _ = runCommand([]string{"fmt"}, `{"path":`)
+	if code != exitError {
+		t.Errorf("Unexpected exit code %d", code)
+	}
+
+	config := filepath.Join(dir, "config.json")
+	err = os.WriteFile(config, []byte(`{"name":"rules","or":{"path":"$.a"}}`), 0644)
+	if err != nil {
+		t.Error("Failed to write file", err)
+		return
+	}
+	code, _, errOut = runCommand([]string{"fmt", "-w", dir}, "")
+	if code != exitError || !strings.Contains(errOut, `config.json: not a filter document: unknown field "name"`) {
+		t.Errorf("Unexpected result %d %q", code, errOut)
+	}
+	data, err = os.ReadFile(config)
+	if err != nil || string(data) != `{"name":"rules","or":{"path":"$.a"}}` {
+		t.Errorf("File which is not a filter was rewritten %q", data)
+	}
+	code, _, _ = runCommand([]string{"fmt"}, `{"path":"$.a","or":{"path":"$.b","valeu":1}}`)
+	if code != exitError {
+		t.Errorf("Unexpected exit code %d", code)
+	}
+}
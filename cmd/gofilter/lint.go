@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	filter "github.com/nickcarenza/go-filter"
+	"github.com/nickcarenza/go-filter/filtertest"
+)
+
+func runLint(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gofilter lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	samplesFile := flags.String("samples", "", "check value types against the messages in `file` (JSON, JSON array or JSON Lines)")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: gofilter lint [-samples file] [dir | filter ...]\n")
+		flags.PrintDefaults()
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return exitError
+	}
+	var samples []interface{}
+	if *samplesFile != "" {
+		samples, err = readSamples(*samplesFile)
+		if err != nil {
+			fmt.Fprintln(stderr, "gofilter:", err)
+			return exitError
+		}
+	}
+	files, err := filterFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, "gofilter:", err)
+		return exitError
+	}
+	code := exitMatch
+	for _, name := range files {
+		f, err := filter.ParseFile(name)
+		if err != nil {
+			fmt.Fprintln(stderr, "gofilter:", err)
+			code = exitError
+			continue
+		}
+		for _, d := range filter.Lint(f, samples...) {
+			fmt.Fprintf(stdout, "%s%s\n", name, d)
+			if d.Severity != filter.SeverityInfo && code == exitMatch {
+				code = exitNoMatch
+			}
+		}
+	}
+	return code
+}
+
+// filterFiles expands directories in paths to the filter documents they
+// contain, skipping test fixtures. Paths default to the current directory.
+func filterFiles(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		var found []string
+		err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filtertest.IsFixture(d.Name()) {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(name)) {
+			case ".json", ".yaml", ".yml", ".toml":
+				found = append(found, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
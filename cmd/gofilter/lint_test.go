@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunLint(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"clean.json":          `{"path":"$.status","operator":">=","value":500}`,
+		"rules/path.yaml":     "path: $.path\noperator: regexMatch\nvalue: api\n",
+		"rules/ids.json":      `{"path":"$.id","operator":"in","value":[1,2]}`,
+		"rules/ids.test.json": `{"expression":"$.id == 1","cases":[]}`,
+		"samples.jsonl":       "{\"id\":\"1\"}\n{\"id\":\"2\"}\n",
+	} {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			t.Error("Failed to write file", err)
+			return
+		}
+	}
+	code, out, errOut := runCommand([]string{"lint", filepath.Join(dir, "clean.json")}, "")
+	if code != exitMatch || out != "" {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+	code, out, errOut = runCommand([]string{"lint", "-samples", filepath.Join(dir, "samples.jsonl"), filepath.Join(dir, "rules")}, "")
+	expected := filepath.Join(dir, "rules/ids.json") + "#/value/0: warning: number 1 is compared with $.id, which holds a string in 2 of 2 samples (type-mismatch)\n" +
+		filepath.Join(dir, "rules/ids.json") + "#/value/1: warning: number 2 is compared with $.id, which holds a string in 2 of 2 samples (type-mismatch)\n" +
+		filepath.Join(dir, "rules/path.yaml") + "#/value: warning: pattern \"api\" is not anchored and matches anywhere in the value (regex-unanchored)\n"
+	if code != exitNoMatch || out != expected {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+	code, _, _ = runCommand([]string{"lint", filepath.Join(dir, "missing.json")}, "")
+	if code != exitError {
+		t.Errorf("Unexpected exit code %d", code)
+	}
+}
@@ -0,0 +1,160 @@
+// Command gofilter filters JSON Lines with go-filter rules.
+//
+//	gofilter -f rule.json < events.jsonl > matched.jsonl
+//	gofilter -e '$.status >= 500 && $.path =~ "^/api"' access.jsonl
+//
+// Records which pass the filter are written to stdout unchanged. The exit
+// status is 0 when at least one record was written, 1 when none were and 2
+// on errors, like grep.
+//
+// Subcommands:
+//
+//	gofilter repl [samples]   author and test filters interactively
+//	gofilter test [dir ...]   run filter test fixtures, see package filtertest
+//	gofilter lint [dir ...]   report likely mistakes in filter documents
+//	gofilter fmt [dir ...]    format filter documents canonically
+//	gofilter diff old new     report semantic changes between two filters
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	filter "github.com/nickcarenza/go-filter"
+)
+
+// Exit codes
+const (
+	exitMatch   = 0
+	exitNoMatch = 1
+	exitError   = 2
+)
+
+// command runs a subcommand with the arguments following its name
+type command func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int
+
+var commands = map[string]command{
+	"repl": runRepl,
+	"test": runTest,
+	"lint": runLint,
+	"fmt":  runFmt,
+	"diff": runDiff,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd(args[1:], stdin, stdout, stderr)
+		}
+	}
+	return runFilter(args, stdin, stdout, stderr)
+}
+
+// runFilter writes the records passing a filter
+func runFilter(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gofilter", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("f", "", "read the filter from `file` (JSON, YAML or TOML by extension)")
+	expr := fs.String("e", "", "filter `expression`, like '$.amount > 100 && $.country in [\"US\"]'")
+	invert := fs.Bool("invert", false, "write the records which do not pass the filter")
+	count := fs.Bool("count", false, "print the number of records written instead of the records")
+	explain := fs.Bool("explain", false, "print the evaluation trace of every record to stderr")
+	errorMode := fs.String("errors", "fail", "on invalid records or evaluation errors: skip, fail or emit them to stderr")
+	workers := fs.Int("workers", 1, "number of records evaluated concurrently")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: gofilter (-f file | -e expression) [flags] [file ...]\n")
+		fmt.Fprintf(stderr, "       gofilter repl [samples]\n")
+		fmt.Fprintf(stderr, "       gofilter test [dir | fixture ...]\n")
+		fmt.Fprintf(stderr, "       gofilter lint [-samples file] [dir | filter ...]\n")
+		fmt.Fprintf(stderr, "       gofilter fmt [-w | -check] [dir | filter.json ...]\n")
+		fmt.Fprintf(stderr, "       gofilter diff [-corpus file] old new\n")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return exitError
+	}
+	if (*file == "") == (*expr == "") {
+		fmt.Fprintln(stderr, "gofilter: exactly one of -f or -e is required")
+		fs.Usage()
+		return exitError
+	}
+	var f *filter.Filter
+	if *file != "" {
+		f, err = filter.ParseFile(*file)
+	} else {
+		f, err = filter.ParseExpression(*expr)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "gofilter:", err)
+		return exitError
+	}
+
+	opts := filter.StreamOptions{Workers: *workers}
+	switch *errorMode {
+	case "skip":
+		opts.Errors = io.Discard
+	case "fail":
+	case "emit":
+		opts.Errors = stderr
+	default:
+		fmt.Fprintf(stderr, "gofilter: invalid -errors %q, expected skip, fail or emit\n", *errorMode)
+		return exitError
+	}
+	if *explain {
+		opts.Explain = stderr
+	}
+	out := stdout
+	if *count {
+		out = io.Discard
+	}
+	matched, rejected := out, io.Discard
+	if *invert {
+		matched, rejected = io.Discard, out
+	}
+	opts.Rejects = rejected
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	written := 0
+	for _, name := range inputs {
+		res, err := streamFile(name, stdin, matched, f, opts)
+		if *invert {
+			written += res.Rejected
+		} else {
+			written += res.Matched
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "gofilter: %s: %s\n", name, err)
+			return exitError
+		}
+	}
+	if *count {
+		fmt.Fprintln(stdout, written)
+	}
+	if written == 0 {
+		return exitNoMatch
+	}
+	return exitMatch
+}
+
+func streamFile(name string, stdin io.Reader, w io.Writer, f *filter.Filter, opts filter.StreamOptions) (filter.StreamResult, error) {
+	if name == "-" {
+		return filter.Stream(stdin, w, f, opts)
+	}
+	r, err := os.Open(name)
+	if err != nil {
+		return filter.StreamResult{}, err
+	}
+	defer r.Close()
+	return filter.Stream(r, w, f, opts)
+}
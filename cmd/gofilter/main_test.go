@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const events = `{"status":200,"path":"/api/users"}
+{"status":503,"path":"/api/orders"}
+{"status":500,"path":"/health"}
+`
+
+func runCommand(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunExpression(t *testing.T) {
+	code, out, errOut := runCommand([]string{"-e", `$.status >= 500 && $.path =~ "^/api"`}, events)
+	if code != exitMatch {
+		t.Errorf("Unexpected exit code %d: %s", code, errOut)
+	}
+	if out != "{\"status\":503,\"path\":\"/api/orders\"}\n" {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+func TestRunFilterFile(t *testing.T) {
+	dir := t.TempDir()
+	rule := filepath.Join(dir, "rule.yaml")
+	err := os.WriteFile(rule, []byte("path: $.status\noperator: '>='\nvalue: 500\n"), 0644)
+	if err != nil {
+		t.Error("Failed to write rule", err)
+		return
+	}
+	input := filepath.Join(dir, "events.jsonl")
+	err = os.WriteFile(input, []byte(events), 0644)
+	if err != nil {
+		t.Error("Failed to write events", err)
+		return
+	}
+	code, out, errOut := runCommand([]string{"-f", rule, "-count", input, "-"}, events)
+	if code != exitMatch {
+		t.Errorf("Unexpected exit code %d: %s", code, errOut)
+	}
+	if out != "4\n" {
+		t.Errorf("Unexpected count %q", out)
+	}
+}
+
+func TestRunInvert(t *testing.T) {
+	code, out, _ := runCommand([]string{"-e", `$.status >= 500`, "-invert"}, events)
+	if code != exitMatch {
+		t.Errorf("Unexpected exit code %d", code)
+	}
+	if out != "{\"status\":200,\"path\":\"/api/users\"}\n" {
+		t.Errorf("Unexpected output %q", out)
+	}
+	code, out, _ = runCommand([]string{"-e", `$.status > 1000`, "-count"}, events)
+	if code != exitNoMatch || out != "0\n" {
+		t.Errorf("Unexpected result %d %q", code, out)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	input := events + "not json\n"
+	code, _, errOut := runCommand([]string{"-e", `$.status == 200`}, input)
+	if code != exitError || !strings.Contains(errOut, "record 4") {
+		t.Errorf("Unexpected result %d %q", code, errOut)
+	}
+	code, out, errOut := runCommand([]string{"-e", `$.status == 200`, "-errors=skip"}, input)
+	if code != exitMatch || errOut != "" || out != "{\"status\":200,\"path\":\"/api/users\"}\n" {
+		t.Errorf("Unexpected result %d %q %q", code, out, errOut)
+	}
+	code, _, errOut = runCommand([]string{"-e", `$.status == 200`, "-errors=emit"}, input)
+	if code != exitMatch || !strings.HasPrefix(errOut, `{"record":4,`) {
+		t.Errorf("Unexpected result %d %q", code, errOut)
+	}
+	for _, args := range [][]string{
+		{},
+		{"-e", "$.a == 1", "-f", "rule.json"},
+		{"-e", "$.a =="},
+		{"-e", "$.a == 1", "-errors=ignore"},
+		{"-e", "$.a == 1", "missing.jsonl"},
+	} {
+		code, _, _ = runCommand(args, events)
+		if code != exitError {
+			t.Errorf("%q should exit with %d, got %d", args, exitError, code)
+		}
+	}
+}
+
+func TestRunExplain(t *testing.T) {
+	_, _, errOut := runCommand([]string{"-e", `$.status == 200`, "-explain"}, events)
+	if !strings.HasPrefix(errOut, "record 1\nPASS $.status eq 200 (actual 200)\nrecord 2\nFAIL") {
+		t.Errorf("Unexpected trace %q", errOut)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	filter "github.com/nickcarenza/go-filter"
+	"github.com/nickcarenza/go-template"
+	"github.com/the-control-group/go-jsonpath"
+)
+
+const replHelp = `Enter a filter as JSON or as an expression to evaluate it against every sample.
+JSON filters may span several lines.
+
+  :load file        load samples from a JSON, JSON array or JSON Lines file
+  :samples          list the loaded samples
+  :path $.path      show the value of a JSONPath in every sample
+  :template tpl     show the output of a template for every sample
+  :filter           print the current filter as JSON
+  :save file        save the current filter as JSON
+  :help             show this help
+  :quit             exit
+`
+
+// repl is an interactive session evaluating filters against samples
+type repl struct {
+	out     io.Writer
+	samples []interface{}
+	filter  *filter.Filter
+}
+
+func runRepl(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gofilter repl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: gofilter repl [samples]\n")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil || fs.NArg() > 1 {
+		return exitError
+	}
+	r := &repl{out: stdout}
+	if fs.NArg() == 1 {
+		err = r.load(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(stderr, "gofilter:", err)
+			return exitError
+		}
+	}
+	fmt.Fprintf(stdout, "%d samples loaded, :help for commands\n", len(r.samples))
+	r.loop(stdin)
+	return exitMatch
+}
+
+func (r *repl) loop(stdin io.Reader) {
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(nil, 1<<24)
+	var pending strings.Builder
+	prompt := "> "
+	for {
+		fmt.Fprint(r.out, prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return
+		}
+		line := scanner.Text()
+		if pending.Len() > 0 || strings.HasPrefix(strings.TrimSpace(line), "{") {
+			// accumulate a JSON filter until it is complete
+			pending.WriteString(line)
+			pending.WriteByte('\n')
+			if !json.Valid([]byte(pending.String())) && strings.TrimSpace(line) != "" {
+				prompt = ". "
+				continue
+			}
+			line = pending.String()
+			pending.Reset()
+			prompt = "> "
+		}
+		if !r.exec(strings.TrimSpace(line)) {
+			return
+		}
+	}
+}
+
+// exec runs one command or filter and reports whether the session continues
+func (r *repl) exec(line string) bool {
+	if line == "" {
+		return true
+	}
+	if !strings.HasPrefix(line, ":") {
+		r.evaluate(line)
+		return true
+	}
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	var err error
+	switch cmd {
+	case ":quit", ":q", ":exit":
+		return false
+	case ":help":
+		fmt.Fprint(r.out, replHelp)
+	case ":load":
+		err = r.load(arg)
+		if err == nil {
+			fmt.Fprintf(r.out, "%d samples loaded\n", len(r.samples))
+		}
+	case ":samples":
+		for i, s := range r.samples {
+			fmt.Fprintf(r.out, "%d: %s\n", i+1, replValue(s, 120))
+		}
+	case ":path":
+		err = r.showPath(arg)
+	case ":template":
+		for i, s := range r.samples {
+			v, err := template.Interpolate(s, arg)
+			if err != nil {
+				fmt.Fprintf(r.out, "%d: error: %s\n", i+1, err)
+				continue
+			}
+			fmt.Fprintf(r.out, "%d: %s\n", i+1, replValue(v, 0))
+		}
+	case ":filter":
+		err = r.printFilter(r.out)
+	case ":save":
+		err = r.save(arg)
+	default:
+		err = fmt.Errorf("unknown command %s, :help for commands", cmd)
+	}
+	if err != nil {
+		fmt.Fprintln(r.out, "error:", err)
+	}
+	return true
+}
+
+// evaluate parses src as a filter document or expression and traces it
+// against every sample
+func (r *repl) evaluate(src string) {
+	var f *filter.Filter
+	var err error
+	if strings.HasPrefix(src, "{") {
+		f, err = filter.ParseJSON([]byte(src))
+	} else {
+		f, err = filter.ParseExpression(src)
+	}
+	if err != nil {
+		fmt.Fprintln(r.out, "error:", err)
+		return
+	}
+	r.filter = f
+	if len(r.samples) == 0 {
+		fmt.Fprintln(r.out, "no samples loaded, :load a file to evaluate the filter")
+		return
+	}
+	passed := 0
+	for i, s := range r.samples {
+		trace, err := f.Explain(s)
+		status := "FAIL"
+		switch {
+		case err != nil:
+			status = "ERROR"
+		case trace.Result:
+			status = "PASS"
+			passed++
+		}
+		fmt.Fprintf(r.out, "sample %d: %s\n", i+1, status)
+		for _, l := range strings.Split(strings.TrimSuffix(trace.String(), "\n"), "\n") {
+			fmt.Fprintf(r.out, "  %s\n", l)
+		}
+	}
+	fmt.Fprintf(r.out, "%d of %d samples passed\n", passed, len(r.samples))
+}
+
+func (r *repl) showPath(src string) error {
+	var path jsonpath.JsonPath
+	quoted, _ := json.Marshal(src)
+	err := path.UnmarshalJSON(quoted)
+	if err != nil {
+		return err
+	}
+	for i, s := range r.samples {
+		v, err := jsonpath.GetPathValue(s, path)
+		if err != nil {
+			fmt.Fprintf(r.out, "%d: missing: %s\n", i+1, err)
+			continue
+		}
+		fmt.Fprintf(r.out, "%d: %s\n", i+1, replValue(v, 0))
+	}
+	return nil
+}
+
+func (r *repl) printFilter(w io.Writer) error {
+	if r.filter == nil {
+		return fmt.Errorf("no filter entered yet")
+	}
+	b, err := json.MarshalIndent(r.filter, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func (r *repl) save(name string) error {
+	if name == "" {
+		return fmt.Errorf("usage: :save file")
+	}
+	var b bytes.Buffer
+	err := r.printFilter(&b)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(name, b.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "saved %s\n", name)
+	return nil
+}
+
+// load replaces the samples with the messages in a file
+func (r *repl) load(name string) error {
+	if name == "" {
+		return fmt.Errorf("usage: :load file")
+	}
+	samples, err := readSamples(name)
+	if err != nil {
+		return err
+	}
+	r.samples = samples
+	return nil
+}
+
+// readSamples reads the messages in a JSON, JSON array or JSON Lines file
+func readSamples(name string) ([]interface{}, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var samples []interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	for {
+		var v interface{}
+		err = dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		samples = append(samples, v)
+	}
+	if len(samples) == 1 {
+		if list, ok := samples[0].([]interface{}); ok {
+			samples = list
+		}
+	}
+	return samples, nil
+}
+
+// replValue formats v as JSON, truncated to max bytes when max is positive
+func replValue(v interface{}, max int) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if max > 0 && len(b) > max {
+		return string(b[:max]) + "..."
+	}
+	return string(b)
+}
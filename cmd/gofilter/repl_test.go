@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	filter "github.com/nickcarenza/go-filter"
+)
+
+func TestRepl(t *testing.T) {
+	dir := t.TempDir()
+	samples := filepath.Join(dir, "events.jsonl")
+	err := os.WriteFile(samples, []byte(events), 0644)
+	if err != nil {
+		t.Error("Failed to write samples", err)
+		return
+	}
+	saved := filepath.Join(dir, "rule.json")
+	input := strings.Join([]string{
+		":samples",
+		"$.status >= 500",
+		"{",
+		`  "path": "$.path",`,
+		`  "operator": "regexMatch", "value": "^/api"`,
+		"}",
+		":path $.status",
+		":template {{.path}}",
+		":save " + saved,
+		":bogus",
+		":quit",
+	}, "\n")
+	code, out, errOut := runCommand([]string{"repl", samples}, input)
+	if code != exitMatch {
+		t.Errorf("Unexpected exit code %d: %s", code, errOut)
+	}
+	for _, expected := range []string{
+		"3 samples loaded",
+		`2: {"path":"/api/orders","status":503}`,
+		"sample 1: FAIL\n  FAIL $.status gte 500 (actual 200)\n",
+		"2 of 3 samples passed",
+		"sample 3: FAIL\n  FAIL $.path regexMatch \"^/api\" (actual \"/health\")\n",
+		"1: 200\n2: 503\n3: 500\n",
+		"1: \"/api/users\"\n",
+		"saved " + saved,
+		"error: unknown command :bogus",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Output should contain %q\n%s", expected, out)
+		}
+	}
+	f, err := filter.ParseFile(saved)
+	if err != nil {
+		t.Error("Failed to load saved filter", err)
+		return
+	}
+	if f.Operator != "regexMatch" {
+		t.Error("Saved filter does not match the last filter entered")
+	}
+}
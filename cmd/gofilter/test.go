@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nickcarenza/go-filter/filtertest"
+)
+
+func runTest(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gofilter test", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	verbose := fs.Bool("v", false, "list every case")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: gofilter test [-v] [dir | fixture ...]\n")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return exitError
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var fixtures []*filtertest.Fixture
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(stderr, "gofilter:", err)
+			return exitError
+		}
+		if info.IsDir() {
+			loaded, err := filtertest.LoadDir(path)
+			if err != nil {
+				fmt.Fprintln(stderr, "gofilter:", err)
+				return exitError
+			}
+			fixtures = append(fixtures, loaded...)
+			continue
+		}
+		fx, err := filtertest.Load(path)
+		if err != nil {
+			fmt.Fprintln(stderr, "gofilter:", err)
+			return exitError
+		}
+		fixtures = append(fixtures, fx)
+	}
+	if len(fixtures) == 0 {
+		fmt.Fprintln(stderr, "gofilter: no fixtures found")
+		return exitError
+	}
+
+	cases, failed := 0, 0
+	for _, fx := range fixtures {
+		for _, r := range fx.Run() {
+			cases++
+			switch {
+			case !r.Passed():
+				failed++
+				fmt.Fprintf(stdout, "FAIL %s", r)
+			case *verbose:
+				fmt.Fprintf(stdout, "ok   %s: %s\n", fx.Path, r.Name())
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(stdout, "FAIL %d of %d cases in %d fixtures\n", failed, cases, len(fixtures))
+		return exitNoMatch
+	}
+	fmt.Fprintf(stdout, "ok   %d cases in %d fixtures\n", cases, len(fixtures))
+	return exitMatch
+}
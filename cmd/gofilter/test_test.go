@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunTest(t *testing.T) {
+	code, out, errOut := runCommand([]string{"test", "-v", "../../filtertest/testdata/rules"}, "")
+	if code != exitMatch {
+		t.Errorf("Unexpected exit code %d: %s%s", code, out, errOut)
+	}
+	if !strings.Contains(out, `ok   ../../filtertest/testdata/rules/status.test.yaml: case 3 "status is not a number"`) ||
+		!strings.HasSuffix(out, "ok   6 cases in 2 fixtures\n") {
+		t.Errorf("Unexpected output %q", out)
+	}
+	code, out, _ = runCommand([]string{"test", "../../filtertest/testdata/failing/amount.test.json"}, "")
+	if code != exitNoMatch {
+		t.Errorf("Unexpected exit code %d", code)
+	}
+	if !strings.Contains(out, "FAIL ../../filtertest/testdata/failing/amount.test.json: case 2 \"boundary\": expected pass, got fail\n    FAIL $.amount gt 100 (actual 100)\n") ||
+		!strings.HasSuffix(out, "FAIL 2 of 3 cases in 1 fixtures\n") {
+		t.Errorf("Unexpected output %q", out)
+	}
+	code, _, _ = runCommand([]string{"test", "missing"}, "")
+	if code != exitError {
+		t.Errorf("Unexpected exit code %d", code)
+	}
+}
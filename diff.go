@@ -0,0 +1,204 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChangeKind classifies a Change
+type ChangeKind string
+
+// Change kinds
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is a difference in meaning between two filters. Location is a JSON
+// Pointer into the new filter, or into the old filter for removed clauses.
+// Modified changes name the Field which changed; added and removed changes
+// describe the clause in New or Old.
+type Change struct {
+	Kind     ChangeKind  `json:"kind"`
+	Location string      `json:"location"`
+	Field    string      `json:"field,omitempty"`
+	Old      interface{} `json:"old,omitempty"`
+	New      interface{} `json:"new,omitempty"`
+}
+
+// String formats the change on one line with the location as a URI fragment
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("added #%s: %s", c.Location, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("removed #%s: %s", c.Location, c.Old)
+	default:
+		return fmt.Sprintf("modified #%s %s: %s -> %s", c.Location, c.Field, traceValue(c.Old), traceValue(c.New))
+	}
+}
+
+// Diff reports the clauses added, removed or modified between old and new.
+// Both filters are canonicalized first so changing an operator alias is not a
+// change. The And chain of the root, and the Or and And chains nested in it,
+// are aligned on their longest common subsequence of identical conditions;
+// conditions between aligned ones are paired up as modified, the rest are
+// added or removed.
+func Diff(old, new *Filter) []Change {
+	d := &differ{}
+	d.chain(filterChain(Canonicalize(old), "", "and"), filterChain(Canonicalize(new), "", "and"))
+	return d.changes
+}
+
+type differ struct {
+	changes []Change
+}
+
+// chainLink is a filter in an Or or And chain. Its nested chain runs along
+// the other clause.
+type chainLink struct {
+	f        *Filter
+	location string
+	nested   string
+}
+
+// filterChain follows the clause named dim from f
+func filterChain(f *Filter, location string, dim string) []chainLink {
+	var links []chainLink
+	nested := "or"
+	if dim == "or" {
+		nested = "and"
+	}
+	for ; f != nil; location = location + "/" + dim {
+		links = append(links, chainLink{f, location, nested})
+		if dim == "or" {
+			f = f.Or
+		} else {
+			f = f.And
+		}
+	}
+	return links
+}
+
+// nestedChain returns the chain hanging off l along its other clause
+func (l chainLink) nestedChain() []chainLink {
+	if l.nested == "or" {
+		return filterChain(l.f.Or, l.location+"/or", "or")
+	}
+	return filterChain(l.f.And, l.location+"/and", "and")
+}
+
+func (d *differ) chain(olds, news []chainLink) {
+	// longest common subsequence of identical conditions
+	lcs := make([][]int, len(olds)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(news)+1)
+	}
+	for i := len(olds) - 1; i >= 0; i-- {
+		for j := len(news) - 1; j >= 0; j-- {
+			switch {
+			case conditionKey(olds[i].f) == conditionKey(news[j].f):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	gapOld, gapNew := 0, 0
+	for i < len(olds) || j < len(news) {
+		if i < len(olds) && j < len(news) && conditionKey(olds[i].f) == conditionKey(news[j].f) {
+			d.gap(olds[gapOld:i], news[gapNew:j])
+			d.pair(olds[i], news[j])
+			i, j = i+1, j+1
+			gapOld, gapNew = i, j
+			continue
+		}
+		if j < len(news) && (i == len(olds) || lcs[i][j+1] >= lcs[i+1][j]) {
+			j++
+		} else {
+			i++
+		}
+	}
+	d.gap(olds[gapOld:], news[gapNew:])
+}
+
+// gap pairs up the unaligned links between two aligned ones
+func (d *differ) gap(olds, news []chainLink) {
+	n := len(olds)
+	if len(news) < n {
+		n = len(news)
+	}
+	for k := 0; k < n; k++ {
+		d.condition(olds[k].f, news[k].f, news[k].location)
+		d.pair(olds[k], news[k])
+	}
+	for _, l := range olds[n:] {
+		d.all(ChangeRemoved, l)
+	}
+	for _, l := range news[n:] {
+		d.all(ChangeAdded, l)
+	}
+}
+
+// pair diffs the nested chains of two aligned links
+func (d *differ) pair(old, new chainLink) {
+	d.chain(old.nestedChain(), new.nestedChain())
+}
+
+// all reports l and everything nested in it as added or removed
+func (d *differ) all(kind ChangeKind, l chainLink) {
+	c := Change{Kind: kind, Location: l.location}
+	if kind == ChangeAdded {
+		c.New = conditionString(l.f)
+	} else {
+		c.Old = conditionString(l.f)
+	}
+	d.changes = append(d.changes, c)
+	for _, n := range l.nestedChain() {
+		d.all(kind, n)
+	}
+}
+
+// condition reports the fields which differ between the conditions of two
+// paired filters
+func (d *differ) condition(old, new *Filter, location string) {
+	modified := func(field string, o, n interface{}) {
+		if !reflect.DeepEqual(numberValues(o), numberValues(n)) {
+			d.changes = append(d.changes, Change{ChangeModified, location, field, o, n})
+		}
+	}
+	modified("template", conditionTemplate(old), conditionTemplate(new))
+	modified("path", old.Path.String(), new.Path.String())
+	modified("operator", old.Operator, new.Operator)
+	modified("value", old.Value, new.Value)
+	modified("requeue", old.Requeue, new.Requeue)
+	modified("onMissing", old.OnMissing, new.OnMissing)
+	modified("script", old.Script, new.Script)
+}
+
+func conditionTemplate(f *Filter) string {
+	if f.Template == nil {
+		return ""
+	}
+	return templateSource(f.Template)
+}
+
+// conditionString describes the condition of f like a Trace line
+func conditionString(f *Filter) string {
+	if f.Script != nil {
+		return fmt.Sprintf("script %s %s", f.Script.Interpreter, traceValue(strings.TrimSpace(f.Script.Script+" "+f.Script.ScriptFile)))
+	}
+	subject := f.Path.String()
+	if f.Template != nil {
+		subject = traceValue(templateSource(f.Template))
+	}
+	if unaryOperator(f.Operator) {
+		return fmt.Sprintf("%s %s", subject, f.Operator)
+	}
+	return fmt.Sprintf("%s %s %s", subject, f.Operator, traceValue(f.Value))
+}
@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		old, new string
+		expected []string
+	}{
+		{
+			`{"path":"$.a","operator":">","value":1,"and":{"path":"$.b","value":"x"}}`,
+			`{"path":"$.a","operator":"greater than","value":1,"and":{"path":"$.b","operator":"==","value":"x"}}`,
+			nil,
+		},
+		{
+			`{"path":"$.a","operator":">","value":1,"and":{"path":"$.b","value":"x"}}`,
+			`{"path":"$.a","operator":">=","value":1,"and":{"path":"$.b","value":"y"}}`,
+			[]string{
+				`modified # operator: "gt" -> "gte"`,
+				`modified #/and value: "x" -> "y"`,
+			},
+		},
+		{
+			`{"path":"$.a","value":1,"and":{"path":"$.c","value":3}}`,
+			`{"path":"$.new","value":0,"and":{"path":"$.a","value":1,"and":{"path":"$.c","value":3,"or":{"path":"$.d","value":4}}}}`,
+			[]string{
+				`added #: $.new eq 0`,
+				`added #/and/and/or: $.d eq 4`,
+			},
+		},
+		{
+			`{"path":"$.a","value":1,"or":{"path":"$.b","value":2,"and":{"path":"$.e","value":5}},"and":{"path":"$.c","value":3}}`,
+			`{"path":"$.a","value":1}`,
+			[]string{
+				`removed #/or: $.b eq 2`,
+				`removed #/or/and: $.e eq 5`,
+				`removed #/and: $.c eq 3`,
+			},
+		},
+		{
+			`{"path":"$.a","value":1,"or":{"path":"$.b","operator":"in","value":[1,2]}}`,
+			`{"path":"$.a","value":1,"or":{"path":"$.b","operator":"in","value":[1,2,3],"requeue":true}}`,
+			[]string{
+				`modified #/or value: [1,2] -> [1,2,3]`,
+				`modified #/or requeue: false -> true`,
+			},
+		},
+		{
+			`{"path":"$.a","value":5,"and":{"path":"$.b","operator":"in","value":[1,2.50]}}`,
+			`{"path":"$.a","value":5.0,"and":{"path":"$.b","operator":"in","value":[1.0,2.5]}}`,
+			nil,
+		},
+	}
+	for _, c := range cases {
+		old, err := ParseJSON([]byte(c.old))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		new, err := ParseJSON([]byte(c.new))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		changes := Diff(old, new)
+		if len(changes) != len(c.expected) {
+			t.Errorf("%s -> %s: expected %d changes, got %v", c.old, c.new, len(c.expected), changes)
+			continue
+		}
+		for i, change := range changes {
+			if change.String() != c.expected[i] {
+				t.Errorf("Expected %s, got %s", c.expected[i], change)
+			}
+		}
+	}
+}
@@ -0,0 +1,562 @@
+package filter
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/the-control-group/go-timeutils"
+)
+
+// ToElasticsearch translates the filter into an Elasticsearch / OpenSearch
+// bool query. Script and Template filters, templated values, values referring
+// to other paths and paths that do not map onto a document field cannot be
+// translated and return an error.
+//
+// Go regular expressions are unanchored while Lucene's are always anchored, so
+// regexMatch patterns are grouped and padded with .* unless ^ or $ anchor them.
+// Patterns are rewritten in Lucene syntax, and those using Go features Lucene
+// lacks, such as \b or anchors inside the pattern, return an error.
+func ToElasticsearch(f *Filter) (map[string]interface{}, error) {
+	q, err := esQuery(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := q["bool"]; ok {
+		return q, nil
+	}
+	return esBool("must", q), nil
+}
+
+// esQuery translates f including its Or and And clauses, which Test evaluates
+// as (f || f.Or) && f.And
+func esQuery(f *Filter) (map[string]interface{}, error) {
+	q, err := esClause(f)
+	if err != nil {
+		return nil, err
+	}
+	if f.Or != nil {
+		or, err := esQuery(f.Or)
+		if err != nil {
+			return nil, err
+		}
+		q = esBool("should", q, or)
+	}
+	if f.And != nil {
+		and, err := esQuery(f.And)
+		if err != nil {
+			return nil, err
+		}
+		q = esBool("must", q, and)
+	}
+	return q, nil
+}
+
+// esClause translates the comparison of a single filter node
+func esClause(f *Filter) (map[string]interface{}, error) {
+	if f.Script != nil {
+		return nil, fmt.Errorf("script filters cannot be translated to elasticsearch")
+	}
+	field, err := esField(f)
+	if err != nil {
+		return nil, err
+	}
+	value, err := esValue(f.Value)
+	if err != nil {
+		return nil, err
+	}
+	op, ok := canonicalOperator(f.Operator)
+	if !ok {
+		op = opEqual
+	}
+	switch op {
+	case opEqual, opNotEqual:
+		if value == nil {
+			// Test treats missing and null alike
+			exists := map[string]interface{}{"exists": map[string]interface{}{"field": field}}
+			if op == opNotEqual {
+				return exists, nil
+			}
+			return esNot(exists), nil
+		}
+		q := map[string]interface{}{"term": map[string]interface{}{field: value}}
+		if op == opNotEqual {
+			return esNot(q), nil
+		}
+		return q, nil
+	case opIn, opNotIn:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("TypeAssertionError")
+		}
+		values := make([]interface{}, len(list))
+		for i, v := range list {
+			values[i], err = esValue(v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		q := map[string]interface{}{"terms": map[string]interface{}{field: values}}
+		if op == opNotIn {
+			return esNot(q), nil
+		}
+		return q, nil
+	case opLessThan, opGreaterThan, opGreaterEqual, opLessEqual:
+		if _, err := interfaceToFloat64(value); err != nil {
+			return nil, err
+		}
+		return esRange(field, op, value), nil
+	case opOlderThan, opNewerThan:
+		d, err := esDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		// time.Since(t) > d is t < now-d
+		if op == opOlderThan {
+			return esRange(field, opLessThan, esDateMath(d)), nil
+		}
+		return esRange(field, opGreaterThan, esDateMath(d)), nil
+	case opBefore, opAfter:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("TypeAssertionError")
+		}
+		if op == opBefore {
+			return esRange(field, opLessThan, str), nil
+		}
+		return esRange(field, opGreaterThan, str), nil
+	case opWithinLast, opWithinNext:
+		d, err := esDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		bounds := map[string]interface{}{"gte": esDateMath(d), "lte": "now"}
+		if op == opWithinNext {
+			bounds = map[string]interface{}{"gte": "now", "lte": esDateMath(-d)}
+		}
+		return map[string]interface{}{"range": map[string]interface{}{field: bounds}}, nil
+	case opRegexMatch, opRegexNoMatch:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("TypeAssertionError")
+		}
+		pattern, err := esRegexp(str)
+		if err != nil {
+			return nil, err
+		}
+		q := map[string]interface{}{"regexp": map[string]interface{}{field: pattern}}
+		if op == opRegexNoMatch {
+			return esNot(q), nil
+		}
+		return q, nil
+	case opExists, opNotExists:
+		q := map[string]interface{}{"exists": map[string]interface{}{"field": field}}
+		if op == opNotExists {
+			return esNot(q), nil
+		}
+		return q, nil
+	case opContains, opNotContains, opStartsWith, opEndsWith, opEqualsIgnoreCase,
+		opContainsIgnoreCase, opNotContainsIgnoreCase, opStartsWithIgnoreCase, opEndsWithIgnoreCase:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("TypeAssertionError")
+		}
+		return esString(field, op, str), nil
+	case opGlob, opNotGlob, opGlobIgnoreCase, opNotGlobIgnoreCase:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("TypeAssertionError")
+		}
+		re, err := globRegexp(str, true)
+		if err != nil {
+			return nil, err
+		}
+		params := map[string]interface{}{"value": re}
+		if op == opGlobIgnoreCase || op == opNotGlobIgnoreCase {
+			params["case_insensitive"] = true
+		}
+		q := map[string]interface{}{"regexp": map[string]interface{}{field: params}}
+		if op == opNotGlob || op == opNotGlobIgnoreCase {
+			return esNot(q), nil
+		}
+		return q, nil
+	case opBetween, opNotBetween:
+		r, err := parseRange(value)
+		if err != nil {
+			return nil, err
+		}
+		bounds := map[string]interface{}{}
+		for _, b := range []struct {
+			v         interface{}
+			inclusive bool
+			op        string
+		}{{r.Min, r.MinInclusive, "gt"}, {r.Max, r.MaxInclusive, "lt"}} {
+			if b.v == nil {
+				continue
+			}
+			b.v, err = esValue(b.v)
+			if err != nil {
+				return nil, err
+			}
+			if b.inclusive {
+				b.op += "e"
+			}
+			bounds[b.op] = b.v
+		}
+		q := map[string]interface{}{"range": map[string]interface{}{field: bounds}}
+		if op == opNotBetween {
+			return esNot(q), nil
+		}
+		return q, nil
+	case opContainsAll, opContainsAny, opContainsNone:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("TypeAssertionError")
+		}
+		terms := make([]map[string]interface{}, len(list))
+		values := make([]interface{}, len(list))
+		for i, v := range list {
+			values[i], err = esValue(v)
+			if err != nil {
+				return nil, err
+			}
+			terms[i] = map[string]interface{}{"term": map[string]interface{}{field: values[i]}}
+		}
+		switch op {
+		case opContainsAll:
+			return esBool("must", terms...), nil
+		case opContainsAny:
+			return map[string]interface{}{"terms": map[string]interface{}{field: values}}, nil
+		default:
+			return esNot(map[string]interface{}{"terms": map[string]interface{}{field: values}}), nil
+		}
+	case opSubsetOf, opEqualSet,
+		opLengthEq, opLengthNe, opLengthLt, opLengthLte, opLengthGt, opLengthGte, opIsEmpty, opIsNotEmpty,
+		opIsType, opIsTimestamp, opIsNumeric:
+		// only scripts can limit a field to listed values, measure it or
+		// inspect its type
+		return nil, fmt.Errorf("operator %s cannot be translated to elasticsearch", f.Operator)
+	case opAnyElement, opAllElements, opNoElement, opCountElements:
+		// elements of object arrays are flattened unless mapped as nested
+		return nil, fmt.Errorf("operator %s cannot be translated to elasticsearch", f.Operator)
+	case opIsNull, opIsNotNull:
+		return nil, fmt.Errorf("operator %s cannot be translated to elasticsearch, which does not index null values", f.Operator)
+	default:
+		return nil, fmt.Errorf("operator %s cannot be translated to elasticsearch", f.Operator)
+	}
+}
+
+// esField converts the filter path to a dotted document field name
+func esField(f *Filter) (string, error) {
+	if f.Template != nil {
+		return "", fmt.Errorf("template filters cannot be translated to elasticsearch")
+	}
+	segments, ok := parsePath(f.Path.String())
+	if !ok || len(segments) == 0 {
+		return "", fmt.Errorf("path %s cannot be translated to an elasticsearch field", f.Path.String())
+	}
+	var names []string
+	for _, s := range segments {
+		switch {
+		case s.Wildcard:
+			// Elasticsearch flattens arrays, so $.items[*].sku is items.sku
+		case s.IsIndex:
+			return "", fmt.Errorf("path %s cannot be translated to an elasticsearch field", f.Path.String())
+		default:
+			names = append(names, s.Key)
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("path %s cannot be translated to an elasticsearch field", f.Path.String())
+	}
+	return strings.Join(names, "."), nil
+}
+
+// esValue rejects values which Test would interpolate against the message
+func esValue(v interface{}) (interface{}, error) {
+	if ref, ok := v.(*PathRef); ok {
+		return nil, fmt.Errorf("value referring to %s cannot be translated to elasticsearch", ref.Path.String())
+	}
+	if str, ok := v.(string); ok && strings.Contains(str, "{{") {
+		return nil, fmt.Errorf("templated value %q cannot be translated to elasticsearch", str)
+	}
+	return v, nil
+}
+
+func esRange(field string, op string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{field: map[string]interface{}{op: value}}}
+}
+
+func esNot(q map[string]interface{}) map[string]interface{} {
+	return esBool("must_not", q)
+}
+
+// esBool builds a bool query, merging nested bool queries of the same kind
+func esBool(kind string, clauses ...map[string]interface{}) map[string]interface{} {
+	var merged []interface{}
+	for _, c := range clauses {
+		if inner, ok := c["bool"].(map[string]interface{}); ok && kind != "must_not" && esOnly(inner, kind) {
+			merged = append(merged, inner[kind].([]interface{})...)
+			continue
+		}
+		merged = append(merged, c)
+	}
+	b := map[string]interface{}{kind: merged}
+	if kind == "should" {
+		b["minimum_should_match"] = 1
+	}
+	return map[string]interface{}{"bool": b}
+}
+
+// esOnly reports whether the bool query consists solely of kind clauses
+func esOnly(b map[string]interface{}, kind string) bool {
+	for k := range b {
+		if k != kind && !(kind == "should" && k == "minimum_should_match") {
+			return false
+		}
+	}
+	return true
+}
+
+// esDuration parses a duration value, rejecting what ParseApproxBigDuration
+// would silently read as 0
+func esDuration(value interface{}) (time.Duration, error) {
+	str, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("TypeAssertionError")
+	}
+	if !durationRegexp.MatchString(str) {
+		return 0, fmt.Errorf("invalid duration %q cannot be translated to elasticsearch", str)
+	}
+	d, err := timeutils.ParseApproxBigDuration([]byte(str))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(d), nil
+}
+
+// esDateMath formats now-d as date math. Sub-second precision is not
+// supported by date math and is truncated.
+func esDateMath(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	switch {
+	case d == 0:
+		return "now"
+	case d < 0:
+		return "now+" + durationString(-d)
+	default:
+		return "now-" + durationString(d)
+	}
+}
+
+// esString translates a string operator to a term, prefix or wildcard query
+func esString(field string, op string, str string) map[string]interface{} {
+	params := map[string]interface{}{}
+	switch op {
+	case opEqualsIgnoreCase, opContainsIgnoreCase, opNotContainsIgnoreCase, opStartsWithIgnoreCase, opEndsWithIgnoreCase:
+		params["case_insensitive"] = true
+	}
+	kind := "wildcard"
+	switch op {
+	case opEqualsIgnoreCase:
+		kind, params["value"] = "term", str
+	case opStartsWith, opStartsWithIgnoreCase:
+		kind, params["value"] = "prefix", str
+	case opEndsWith, opEndsWithIgnoreCase:
+		params["value"] = "*" + esWildcardEscape(str)
+	default:
+		params["value"] = "*" + esWildcardEscape(str) + "*"
+	}
+	q := map[string]interface{}{kind: map[string]interface{}{field: params}}
+	if op == opNotContains || op == opNotContainsIgnoreCase {
+		return esNot(q)
+	}
+	return q
+}
+
+var esWildcardReplacer = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`)
+
+// esWildcardEscape escapes the wildcard characters of a literal string
+func esWildcardEscape(s string) string {
+	return esWildcardReplacer.Replace(s)
+}
+
+// esRegexp translates a Go pattern into Lucene syntax. Lucene patterns are
+// always anchored and treat ^ and $ as literals, so anchors are dropped from
+// the ends of the pattern and an unanchored end is padded with .*. Anchors
+// elsewhere, word boundaries and multi-line anchors have no Lucene equivalent
+// and are an error.
+func esRegexp(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	begin := len(subs) > 0 && subs[0].Op == syntax.OpBeginText
+	if begin {
+		subs = subs[1:]
+	}
+	end := len(subs) > 0 && subs[len(subs)-1].Op == syntax.OpEndText
+	if end {
+		subs = subs[:len(subs)-1]
+	}
+	var b strings.Builder
+	for _, sub := range subs {
+		err = writeLucene(&b, sub, len(subs) > 1 && sub.Op == syntax.OpAlternate)
+		if err != nil {
+			return "", fmt.Errorf("regular expression %q cannot be translated to elasticsearch: %w", pattern, err)
+		}
+	}
+	lucene := b.String()
+	switch {
+	case !begin && !end:
+		return ".*(" + lucene + ").*", nil
+	case !begin:
+		return ".*(" + lucene + ")", nil
+	case !end:
+		return "(" + lucene + ").*", nil
+	}
+	return lucene, nil
+}
+
+// luceneReserved are the characters Lucene's regexp syntax escapes, with all
+// optional operators enabled as Elasticsearch does by default
+const luceneReserved = `.?+*|{}[]()"\#@&<>~^$`
+
+// writeLucene writes re in Lucene syntax, in parentheses if group is set
+func writeLucene(b *strings.Builder, re *syntax.Regexp, group bool) error {
+	if group {
+		b.WriteByte('(')
+		defer b.WriteByte(')')
+	}
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		b.WriteString("()")
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && unicode.SimpleFold(r) != r {
+				// Lucene has no case-insensitive flag, list the cases instead
+				b.WriteByte('[')
+				for f := r; ; {
+					writeLuceneRune(b, f)
+					if f = unicode.SimpleFold(f); f == r {
+						break
+					}
+				}
+				b.WriteByte(']')
+				continue
+			}
+			writeLuceneRune(b, r)
+		}
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return fmt.Errorf("empty character classes are not supported")
+		}
+		writeLuceneClass(b, re.Rune)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte('.')
+	case syntax.OpCapture:
+		return writeLucene(b, re.Sub[0], true)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		sub := re.Sub[0]
+		atom := sub.Op == syntax.OpCharClass || sub.Op == syntax.OpAnyChar || sub.Op == syntax.OpAnyCharNotNL ||
+			sub.Op == syntax.OpCapture || (sub.Op == syntax.OpLiteral && len(sub.Rune) == 1 && sub.Flags&syntax.FoldCase == 0)
+		err := writeLucene(b, sub, !atom)
+		if err != nil {
+			return err
+		}
+		switch {
+		case re.Op == syntax.OpStar:
+			b.WriteByte('*')
+		case re.Op == syntax.OpPlus:
+			b.WriteByte('+')
+		case re.Op == syntax.OpQuest:
+			b.WriteByte('?')
+		case re.Max == re.Min:
+			fmt.Fprintf(b, "{%d}", re.Min)
+		case re.Max < 0:
+			fmt.Fprintf(b, "{%d,}", re.Min)
+		default:
+			fmt.Fprintf(b, "{%d,%d}", re.Min, re.Max)
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			err := writeLucene(b, sub, sub.Op == syntax.OpAlternate)
+			if err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			if i > 0 {
+				b.WriteByte('|')
+			}
+			err := writeLucene(b, sub, false)
+			if err != nil {
+				return err
+			}
+		}
+	case syntax.OpBeginText, syntax.OpEndText:
+		return fmt.Errorf("^ and $ are only supported at the start and end of the pattern")
+	case syntax.OpBeginLine, syntax.OpEndLine:
+		return fmt.Errorf("multi-line anchors are not supported")
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return fmt.Errorf("word boundaries are not supported")
+	default:
+		return fmt.Errorf("unsupported expression %s", re)
+	}
+	return nil
+}
+
+// writeLuceneClass writes the character class of the sorted rune ranges,
+// negated when that is shorter as Go stores [^a] as its complement
+func writeLuceneClass(b *strings.Builder, ranges []rune) {
+	negate := len(ranges) > 0 && ranges[0] == 0 && ranges[len(ranges)-1] == unicode.MaxRune
+	if negate {
+		var complement []rune
+		for i := 1; i < len(ranges); i += 2 {
+			if i+1 < len(ranges) {
+				complement = append(complement, ranges[i]+1, ranges[i+1]-1)
+			}
+		}
+		if len(complement) == 0 {
+			b.WriteByte('.')
+			return
+		}
+		ranges = complement
+	}
+	b.WriteByte('[')
+	if negate {
+		b.WriteByte('^')
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		writeLuceneClassRune(b, lo)
+		if hi > lo+1 {
+			b.WriteByte('-')
+		}
+		if hi > lo {
+			writeLuceneClassRune(b, hi)
+		}
+	}
+	b.WriteByte(']')
+}
+
+func writeLuceneClassRune(b *strings.Builder, r rune) {
+	if r == '-' {
+		b.WriteByte('\\')
+	}
+	writeLuceneRune(b, r)
+}
+
+func writeLuceneRune(b *strings.Builder, r rune) {
+	if strings.ContainsRune(luceneReserved, r) {
+		b.WriteByte('\\')
+	}
+	b.WriteRune(r)
+}
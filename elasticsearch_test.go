@@ -0,0 +1,328 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestToElasticsearch(t *testing.T) {
+	var filter = Filter{}
+	dec := json.NewDecoder(bytes.NewBuffer([]byte(`{"path":"$.amount","operator":">","value":100,"or":{"path":"$.country","operator":"in","value":["US","CA"]},"and":{"path":"$.status","operator":"!=","value":"void"}}`)))
+	dec.UseNumber()
+	err := dec.Decode(&filter)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	q, err := ToElasticsearch(&filter)
+	if err != nil {
+		t.Error("Failed to translate filter", err)
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"bool":{"must":[{"bool":{"minimum_should_match":1,"should":[{"range":{"amount":{"gt":100}}},{"terms":{"country":["US","CA"]}}]}},{"bool":{"must_not":[{"term":{"status":"void"}}]}}]}}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+}
+
+func TestToElasticsearchDateMath(t *testing.T) {
+	var filter = Filter{}
+	err := json.Unmarshal([]byte(`{"path":"$.created[\"at\"]","operator":"olderThan","value":"5m","and":{"path":"$.updated","operator":"newer than","value":"2d"}}`), &filter)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	q, err := ToElasticsearch(&filter)
+	if err != nil {
+		t.Error("Failed to translate filter", err)
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"bool":{"must":[{"range":{"created.at":{"lt":"now-5m"}}},{"range":{"updated":{"gt":"now-2d"}}}]}}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+}
+
+func TestToElasticsearchInvalidDuration(t *testing.T) {
+	for _, src := range []string{
+		`{"path":"$.created","operator":"olderThan","value":"soon"}`,
+		`{"path":"$.expires","operator":"withinNext","value":"a week"}`,
+	} {
+		filter, err := ParseJSON([]byte(src))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		_, err = ToElasticsearch(filter)
+		if err == nil {
+			t.Errorf("%s: invalid duration should not translate", src)
+		}
+	}
+}
+
+func TestToElasticsearchRegexAndNull(t *testing.T) {
+	var filter = Filter{}
+	err := json.Unmarshal([]byte(`{"path":"$.host","operator":"regexMatch","value":"^api-[0-9]+","and":{"path":"$.deleted","value":null}}`), &filter)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	q, err := ToElasticsearch(&filter)
+	if err != nil {
+		t.Error("Failed to translate filter", err)
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"bool":{"must":[{"regexp":{"host":"(api-[0-9]+).*"}},{"bool":{"must_not":[{"exists":{"field":"deleted"}}]}}]}}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+}
+
+func TestToElasticsearchUntranslatable(t *testing.T) {
+	var filter = Filter{}
+	err := json.Unmarshal([]byte(`{"template":"{{.value}}","value":"test"}`), &filter)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	_, err = ToElasticsearch(&filter)
+	if err == nil {
+		t.Error("template filter should not translate")
+		return
+	}
+	err = json.Unmarshal([]byte(`{"path":"$.value","value":"{{.other}}"}`), &filter)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	filter.Template = nil
+	_, err = ToElasticsearch(&filter)
+	if err == nil {
+		t.Error("templated value should not translate")
+		return
+	}
+}
+
+func TestToElasticsearchExists(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.email","operator":"exists","and":{"path":"$.deleted","operator":"notExists"}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	q, err := ToElasticsearch(filter)
+	if err != nil {
+		t.Error("Failed to translate filter", err)
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"bool":{"must":[{"exists":{"field":"email"}},{"bool":{"must_not":[{"exists":{"field":"deleted"}}]}}]}}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+	filter, err = ParseJSON([]byte(`{"path":"$.deleted","operator":"isNull"}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	_, err = ToElasticsearch(filter)
+	if err == nil {
+		t.Error("isNull should not translate")
+	}
+}
+
+func TestToElasticsearchStrings(t *testing.T) {
+	cases := []struct {
+		filter   string
+		expected string
+	}{
+		{`{"path":"$.message","operator":"contains","value":"a*b"}`, `{"bool":{"must":[{"wildcard":{"message":{"value":"*a\\*b*"}}}]}}`},
+		{`{"path":"$.message","operator":"notContainsIgnoreCase","value":"x"}`, `{"bool":{"must_not":[{"wildcard":{"message":{"case_insensitive":true,"value":"*x*"}}}]}}`},
+		{`{"path":"$.host","operator":"startsWith","value":"api-"}`, `{"bool":{"must":[{"prefix":{"host":{"value":"api-"}}}]}}`},
+		{`{"path":"$.host","operator":"endsWith","value":".com"}`, `{"bool":{"must":[{"wildcard":{"host":{"value":"*.com"}}}]}}`},
+		{`{"path":"$.host","operator":"equalsIgnoreCase","value":"API"}`, `{"bool":{"must":[{"term":{"host":{"case_insensitive":true,"value":"API"}}}]}}`},
+	}
+	for _, c := range cases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		q, err := ToElasticsearch(filter)
+		if err != nil {
+			t.Error("Failed to translate filter", err)
+			return
+		}
+		b, err := json.Marshal(q)
+		if err != nil {
+			t.Error("Error marshaling to json", err)
+			return
+		}
+		if string(b) != c.expected {
+			t.Errorf("%s: unexpected query %s", c.filter, b)
+		}
+	}
+}
+
+func TestToElasticsearchGlob(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.url","operator":"notIGlob","value":"/v1/**/orders/*.json"}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	q, err := ToElasticsearch(filter)
+	if err != nil {
+		t.Error("Failed to translate filter", err)
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"bool":{"must_not":[{"regexp":{"url":{"case_insensitive":true,"value":"/v1/(.*/)?orders/[^/]*\\.json"}}}]}}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+}
+
+func TestToElasticsearchBetween(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.price","operator":"between","value":{"min":10,"max":20,"maxInclusive":false},"and":{"path":"$.qty","operator":"notBetween","value":[1,5]}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	q, err := ToElasticsearch(filter)
+	if err != nil {
+		t.Error("Failed to translate filter", err)
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"bool":{"must":[{"range":{"price":{"gte":10,"lt":20}}},{"bool":{"must_not":[{"range":{"qty":{"gte":1,"lte":5}}}]}}]}}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+}
+
+func TestToElasticsearchSets(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.tags","operator":"containsAll","value":["vip","eu"],"and":{"path":"$.tags","operator":"containsNone","value":["banned"]}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	q, err := ToElasticsearch(filter)
+	if err != nil {
+		t.Error("Failed to translate filter", err)
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"bool":{"must":[{"term":{"tags":"vip"}},{"term":{"tags":"eu"}},{"bool":{"must_not":[{"terms":{"tags":["banned"]}}]}}]}}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+	filter, err = ParseJSON([]byte(`{"path":"$.tags","operator":"subsetOf","value":["vip"]}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	_, err = ToElasticsearch(filter)
+	if err == nil {
+		t.Error("subsetOf should not translate")
+	}
+}
+
+func TestToElasticsearchPathRef(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.spent","operator":">","value":{"path":"$.budget"}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	_, err = ToElasticsearch(filter)
+	if err == nil {
+		t.Error("value referring to a path should not translate")
+	}
+}
+
+func TestToElasticsearchTimes(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.starts","operator":"before","value":"2024-03-01T00:00:00Z","and":{"path":"$.expires","operator":"withinNext","value":"7d","and":{"path":"$.created","operator":"withinLast","value":"1h"}}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	q, err := ToElasticsearch(filter)
+	if err != nil {
+		t.Error("Failed to translate filter", err)
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Error("Error marshaling to json", err)
+		return
+	}
+	if string(b) != `{"bool":{"must":[{"range":{"starts":{"lt":"2024-03-01T00:00:00Z"}}},{"range":{"expires":{"gte":"now","lte":"now+7d"}}},{"range":{"created":{"gte":"now-1h","lte":"now"}}}]}}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+}
+
+func TestESRegexp(t *testing.T) {
+	cases := []struct {
+		pattern string
+		lucene  string
+	}{
+		{`cat|dog`, `.*(cat|dog).*`},
+		{`a|b`, `.*([ab]).*`},
+		{`^(cat|dog)$`, `(cat|dog)`},
+		{`^(?:cat|dog)-\w$`, `(cat|dog)-[0-9A-Z_a-z]`},
+		{`^api`, `(api).*`},
+		{`log$`, `.*(log)`},
+		{`cost\$`, `.*(cost\$).*`},
+		{`a\\$`, `.*(a\\)`},
+		{`^v\d{2,}\.x+$`, `v[0-9]{2,}\.x+`},
+		{`(?:ab)*[^/]?`, `.*((ab)*[^/]?).*`},
+		{`(?i)ok`, `.*([Oo][KkK]).*`},
+		{`^$`, ``},
+	}
+	for _, c := range cases {
+		got, err := esRegexp(c.pattern)
+		if err != nil {
+			t.Errorf("%s: %s", c.pattern, err)
+			continue
+		}
+		if got != c.lucene {
+			t.Errorf("%s: expected %s, got %s", c.pattern, c.lucene, got)
+		}
+	}
+	for _, pattern := range []string{`[`, `^a|b`, `^a|b$`, `a^b`, `(a$)b`, `\bword\b`, `(?m)^a$`, `[^\x00-\x{10FFFF}]`} {
+		if _, err := esRegexp(pattern); err == nil {
+			t.Errorf("%s should fail", pattern)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// elementCount is the value of countElements: the number of elements passing
+// Filter, or of all elements when Filter is nil, compared with Operator and
+// Value
+type elementCount struct {
+	Filter   *Filter     `json:"filter"`
+	Operator string      `json:"operator"`
+	Value    interface{} `json:"value"`
+}
+
+// countComparisons are the operators countElements compares the count with
+var countComparisons = []string{opEqual, opNotEqual, opLessThan, opGreaterThan, opGreaterEqual, opLessEqual, opBetween, opNotBetween}
+
+func isCountComparison(op string) bool {
+	name, _ := canonicalOperator(op)
+	for _, c := range countComparisons {
+		if name == c {
+			return true
+		}
+	}
+	return false
+}
+
+// isElementOperator reports whether the canonical operator op evaluates a
+// nested filter against the elements of an array
+func isElementOperator(op string) bool {
+	switch op {
+	case opAnyElement, opAllElements, opNoElement, opCountElements:
+		return true
+	}
+	return false
+}
+
+// decodeElementFilter replaces the value of f by the filter it describes if
+// f is an element operator, so it is decoded once
+func decodeElementFilter(f *Filter) error {
+	op, _ := canonicalOperator(f.Operator)
+	if !isElementOperator(op) {
+		return nil
+	}
+	var err error
+	f.Value, err = elementValue(op, f.Value)
+	return err
+}
+
+// elementValue converts the value of the element operator op to a *Filter,
+// or an *elementCount for countElements
+func elementValue(op string, v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case *Filter, *elementCount:
+		return v, nil
+	case map[string]interface{}:
+	default:
+		return nil, fmt.Errorf("%s expects a filter", op)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if op == opCountElements {
+		c := &elementCount{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		dec.DisallowUnknownFields()
+		err = dec.Decode(c)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if !isCountComparison(c.Operator) {
+			return nil, fmt.Errorf("%s does not support operator %q", op, c.Operator)
+		}
+		return c, nil
+	}
+	nested, err := ParseJSON(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return nested, nil
+}
+
+// nestedFilter returns the filter an element operator evaluates against each
+// element, nil if there is none or f is not an element operator
+func nestedFilter(f *Filter) *Filter {
+	switch v := f.Value.(type) {
+	case *Filter:
+		return v
+	case *elementCount:
+		return v.Filter
+	}
+	return nil
+}
+
+// testElements evaluates the element operator op against the array val
+func testElements(f *Filter, op string, val interface{}) (bool, error) {
+	if val == nil {
+		return false, nil
+	}
+	elements, ok := val.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("TypeAssertionError")
+	}
+	v, err := elementValue(op, f.Value)
+	if err != nil {
+		return false, err
+	}
+	var nested *Filter
+	var count *elementCount
+	if op == opCountElements {
+		count = v.(*elementCount)
+		nested = count.Filter
+	} else {
+		nested = v.(*Filter)
+	}
+	n := 0
+	for _, e := range elements {
+		pass := true
+		if nested != nil {
+			pass, err = nested.Test(e)
+			if err != nil {
+				return false, err
+			}
+		}
+		switch {
+		case pass && op == opAnyElement:
+			return true, nil
+		case pass && op == opNoElement:
+			return false, nil
+		case !pass && op == opAllElements:
+			return false, nil
+		case pass:
+			n++
+		}
+	}
+	switch op {
+	case opAnyElement:
+		return false, nil
+	case opCountElements:
+		fVal := count.Value
+		if num, ok := fVal.(json.Number); ok {
+			fVal, err = num.Float64()
+			if err != nil {
+				return false, fmt.Errorf("TypeAssertionError")
+			}
+		}
+		return testOperator(&Filter{Operator: count.Operator, Value: count.Value}, nil, float64(n), fVal)
+	default:
+		return true, nil
+	}
+}
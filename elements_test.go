@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/the-control-group/go-jsonpath"
+)
+
+func TestElementOperators(t *testing.T) {
+	msg, err := decodeJSONMessage([]byte(`{
+		"network": [
+			{"left":"BILLED_TO","link":"CreditCard","overusers":1,"right":"BILLED_TO","total":2},
+			{"left":"SHIPPED_TO","link":"Address","overusers":0,"right":"SHIPPED_TO","total":1}
+		],
+		"tags": ["vip", "beta"],
+		"empty": [],
+		"name": "x"
+	}`))
+	if err != nil {
+		t.Error("Failed to parse message", err)
+		return
+	}
+	billed := `{"path":"$.left","value":"BILLED_TO","and":{"path":"$.right","value":"BILLED_TO","and":{"path":"$.link","value":"CreditCard","and":{"path":"$.overusers","operator":">","value":0}}}}`
+	testFilterCases(t, msg, []filterCase{
+		{`{"path":"$.network","operator":"anyElement","value":` + billed + `}`, true},
+		{`{"path":"$.network","operator":"allElements","value":` + billed + `}`, false},
+		{`{"path":"$.network","operator":"noElement","value":` + billed + `}`, false},
+		{`{"path":"$.network","operator":"all","value":{"path":"$.total","operator":">=","value":1}}`, true},
+		{`{"path":"$.tags","operator":"any","value":{"path":"$","value":"vip"}}`, true},
+		{`{"path":"$.tags","operator":"none","value":{"path":"$","operator":"startsWith","value":"ad"}}`, true},
+		{`{"path":"$.empty","operator":"anyElement","value":{"path":"$","value":1}}`, false},
+		{`{"path":"$.empty","operator":"allElements","value":{"path":"$","value":1}}`, true},
+		{`{"path":"$.missing","operator":"anyElement","value":{"path":"$","value":1}}`, false},
+		{`{"path":"$.network","operator":"countElements","value":{"filter":{"path":"$.total","operator":">","value":1},"operator":"==","value":1}}`, true},
+		{`{"path":"$.network","operator":"count","value":{"operator":"gte","value":3}}`, false},
+		{`{"path":"$.tags","operator":"count","value":{"operator":"between","value":[1,2]}}`, true},
+	})
+	for _, f := range []string{
+		`{"path":"$.network","operator":"anyElement","value":1}`,
+		`{"path":"$.network","operator":"countElements","value":{"operator":"in","value":[1]}}`,
+		`{"path":"$.network","operator":"countElements","value":{"operator":"eq","value":1,"where":{}}}`,
+	} {
+		_, err := ParseJSON([]byte(f))
+		if err == nil {
+			t.Errorf("%s should fail to parse", f)
+		}
+	}
+	testFilterErrors(t, msg, []string{
+		`{"path":"$.name","operator":"anyElement","value":{"path":"$","value":1}}`,
+	})
+}
+
+func TestElementOperatorsUndecoded(t *testing.T) {
+	// filters built without decoding decode the nested filter when tested
+	filter := &Filter{Path: jsonpath.MustParsePath("$.tags"), Operator: "anyElement", Value: map[string]interface{}{"path": "$", "value": "vip"}}
+	pass, err := filter.Test(map[string]interface{}{"tags": []interface{}{"beta", "vip"}})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("tags holding vip should pass")
+	}
+}
+
+func TestElementFormat(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.items","operator":"count","value":{"operator":">=","value":2,"filter":{"path":"$.qty","operator":">","value":1}}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	b, err := Format(filter)
+	if err != nil {
+		t.Error("Failed to format filter", err)
+		return
+	}
+	expected := `{
+  "path": "$.items",
+  "value": {
+    "filter": {
+      "path": "$.qty",
+      "value": 1,
+      "operator": "gt"
+    },
+    "operator": "gte",
+    "value": 2
+  },
+  "operator": "countElements"
+}
+`
+	if string(b) != expected {
+		t.Errorf("Unexpected format\n%s", b)
+	}
+	if filter.Value.(*elementCount).Operator != ">=" {
+		t.Error("Format should not modify the filter")
+	}
+}
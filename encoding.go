@@ -0,0 +1,261 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// Decoder decodes an encoded message into the value shapes Test expects
+type Decoder func(data []byte) (interface{}, error)
+
+// TestDecode decodes data with decode and evaluates the filter against it
+func (f *Filter) TestDecode(decode Decoder, data []byte) (bool, error) {
+	msg, err := decode(data)
+	if err != nil {
+		return false, err
+	}
+	return f.Test(msg)
+}
+
+// TestMsgpack evaluates the filter against a MessagePack encoded message
+func (f *Filter) TestMsgpack(data []byte) (bool, error) {
+	return f.TestDecode(DecodeMsgpack, data)
+}
+
+// TestCBOR evaluates the filter against a CBOR encoded message
+func (f *Filter) TestCBOR(data []byte) (bool, error) {
+	return f.TestDecode(DecodeCBOR, data)
+}
+
+// DecodeMsgpack decodes MessagePack into the shapes encoding/json produces
+// with UseNumber. Integers of every width and floats become json.Number,
+// binary becomes a base64 string as encoding/json marshals []byte, timestamps
+// become RFC 3339 strings and other extension types the base64 of their
+// payload. Non-string map keys are formatted with fmt.
+func DecodeMsgpack(data []byte) (interface{}, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.UseLooseInterfaceDecoding(true)
+	return decodeMsgpackValue(dec)
+}
+
+func decodeMsgpackValue(dec *msgpack.Decoder) (interface{}, error) {
+	c, err := dec.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case msgpcode.IsBin(c):
+		b, err := dec.DecodeBytes()
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case msgpcode.IsExt(c):
+		id, n, err := dec.DecodeExtHeader()
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		err = dec.ReadFull(b)
+		if err != nil {
+			return nil, err
+		}
+		if id == -1 {
+			t, err := msgpackTimestamp(b)
+			if err != nil {
+				return nil, err
+			}
+			return t.Format(time.RFC3339Nano), nil
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case msgpcode.IsFixedMap(c) || c == msgpcode.Map16 || c == msgpcode.Map32:
+		n, err := dec.DecodeMapLen()
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k, err := decodeMsgpackValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeMsgpackValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			m[mapKeyString(k)] = v
+		}
+		return m, nil
+	case msgpcode.IsFixedArray(c) || c == msgpcode.Array16 || c == msgpcode.Array32:
+		n, err := dec.DecodeArrayLen()
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		s := make([]interface{}, n)
+		for i := range s {
+			s[i], err = decodeMsgpackValue(dec)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	default:
+		v, err := dec.DecodeInterfaceLoose()
+		if err != nil {
+			return nil, err
+		}
+		return normalizeDecoded(v)
+	}
+}
+
+// msgpackTimestamp decodes the payload of the timestamp extension type
+func msgpackTimestamp(b []byte) (time.Time, error) {
+	switch len(b) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0).UTC(), nil
+	case 8:
+		v := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(v&0x3ffffffff), int64(v>>34)).UTC(), nil
+	case 12:
+		nanos := binary.BigEndian.Uint32(b)
+		secs := int64(binary.BigEndian.Uint64(b[4:]))
+		return time.Unix(secs, int64(nanos)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid msgpack timestamp length %d", len(b))
+	}
+}
+
+// DecodeCBOR decodes CBOR into the shapes encoding/json produces with
+// UseNumber. Integers, bignums and floats become json.Number, byte strings
+// become base64 strings, date/time tags become RFC 3339 strings and other
+// tags are replaced by their content. Non-string map keys are formatted with
+// fmt.
+func DecodeCBOR(data []byte) (interface{}, error) {
+	var v interface{}
+	err := cbor.Unmarshal(data, &v)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeDecoded(v)
+}
+
+func mapKeyString(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	n, err := normalizeDecoded(k)
+	if err != nil {
+		return fmt.Sprint(k)
+	}
+	return fmt.Sprint(n)
+}
+
+// normalizeDecoded converts values decoded from YAML, TOML, MessagePack or
+// CBOR to the shapes encoding/json produces with UseNumber
+func normalizeDecoded(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string, json.Number:
+		return v, nil
+	case int:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int8:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int16:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int32:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), nil
+	case uint:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint8:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint16:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint32:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint64:
+		return json.Number(strconv.FormatUint(v, 10)), nil
+	case float32:
+		return normalizeFloat(float64(v), 32)
+	case float64:
+		return normalizeFloat(v, 64)
+	case big.Int:
+		return json.Number(v.String()), nil
+	case *big.Int:
+		return json.Number(v.String()), nil
+	case cbor.SimpleValue:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case cbor.Tag:
+		return normalizeDecoded(v.Content)
+	case cbor.ByteString:
+		return base64.StdEncoding.EncodeToString([]byte(v)), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case fmt.Stringer:
+		// toml local dates and times
+		return v.String(), nil
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			n, err := normalizeDecoded(e)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = n
+		}
+		return s, nil
+	case []map[string]interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			n, err := normalizeDecoded(e)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = n
+		}
+		return s, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			n, err := normalizeDecoded(e)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = n
+		}
+		return m, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			n, err := normalizeDecoded(e)
+			if err != nil {
+				return nil, err
+			}
+			m[mapKeyString(k)] = n
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %T", v)
+	}
+}
+
+func normalizeFloat(v float64, bits int) (interface{}, error) {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return nil, fmt.Errorf("unsupported number %v", v)
+	}
+	return json.Number(strconv.FormatFloat(v, 'g', -1, bits)), nil
+}
@@ -0,0 +1,125 @@
+package filter
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var encodingCases = []struct {
+	filter string
+	pass   bool
+}{
+	{`{"path":"$.int8","value":-3}`, true},
+	{`{"path":"$.uint16","operator":">","value":400}`, true},
+	{`{"path":"$.int64","operator":"in","value":[1,5000000000]}`, true},
+	{`{"path":"$.float","value":1.5}`, true},
+	{`{"path":"$.float32","value":0.25}`, true},
+	{`{"path":"$.name","value":"bob"}`, true},
+	{`{"path":"$.ok","value":true}`, true},
+	{`{"path":"$.none","value":null}`, true},
+	{`{"path":"$.list[1]","value":2}`, true},
+	{`{"path":"$.nested.k","value":"v"}`, true},
+	{`{"path":"$.bytes","value":"AQI="}`, true},
+	{`{"path":"$.at","value":"2024-01-02T03:04:05Z"}`, true},
+	{`{"path":"$.keys[\"7\"]","value":"seven"}`, true},
+	{`{"path":"$.name","value":"alice"}`, false},
+}
+
+func encodingMessage() map[string]interface{} {
+	return map[string]interface{}{
+		"int8":    int8(-3),
+		"uint16":  uint16(500),
+		"int64":   int64(5000000000),
+		"float":   1.5,
+		"float32": float32(0.25),
+		"name":    "bob",
+		"ok":      true,
+		"none":    nil,
+		"list":    []interface{}{1, 2, 3},
+		"nested":  map[string]interface{}{"k": "v"},
+		"bytes":   []byte{1, 2},
+		"at":      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"keys":    map[int]string{7: "seven"},
+	}
+}
+
+func TestFilterTestMsgpack(t *testing.T) {
+	data, err := msgpack.Marshal(encodingMessage())
+	if err != nil {
+		t.Error("Failed to encode message", err)
+		return
+	}
+	for _, c := range encodingCases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		pass, err := filter.TestMsgpack(data)
+		if err != nil {
+			t.Errorf("%s: Filter test failed %s", c.filter, err)
+			continue
+		}
+		if pass != c.pass {
+			t.Errorf("%s should pass: %t", c.filter, c.pass)
+		}
+	}
+}
+
+func TestFilterTestCBOR(t *testing.T) {
+	mode, err := cbor.EncOptions{Time: cbor.TimeRFC3339, TimeTag: cbor.EncTagRequired}.EncMode()
+	if err != nil {
+		t.Error("Failed to create encoder", err)
+		return
+	}
+	data, err := mode.Marshal(encodingMessage())
+	if err != nil {
+		t.Error("Failed to encode message", err)
+		return
+	}
+	for _, c := range encodingCases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		pass, err := filter.TestCBOR(data)
+		if err != nil {
+			t.Errorf("%s: Filter test failed %s", c.filter, err)
+			continue
+		}
+		if pass != c.pass {
+			t.Errorf("%s should pass: %t", c.filter, c.pass)
+		}
+	}
+}
+
+func TestDecodeCBORBignum(t *testing.T) {
+	n, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if !ok {
+		t.Error("Failed to create bignum")
+		return
+	}
+	data, err := cbor.Marshal(map[string]interface{}{"big": n})
+	if err != nil {
+		t.Error("Failed to encode message", err)
+		return
+	}
+	filter, err := ParseJSON([]byte(`{"path":"$.big","operator":">","value":1e28}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	pass, err := filter.TestCBOR(data)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("Bignum should compare as a number")
+	}
+}
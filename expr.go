@@ -0,0 +1,296 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/the-control-group/go-jsonpath"
+)
+
+// ParseExpression parses a filter written as an expression, as accepted by
+// the gofilter command:
+//
+//	$.amount > 100 && ($.country in ["US", "CA"] || $.vip == true)
+//
+// A comparison is a JSONPath, an operator and a JSON value or another JSONPath,
+// which refers to the value there as {"path": ...} does. Operators are
+// ==, !=, <, <=, >, >=, =~ (regexMatch), !~ (regexNoMatch) or any single word
+// operator name or alias, plus "not in". Operators which ignore the value,
+// like exists and isNull, are written without one. anyElement, allElements and
+// noElement take a parenthesized expression evaluated against each element,
+// with $ referring to the element:
+//
+//	$.items anyElement ($.sku == "A1" && $.qty > 1)
+//
+// Values which are not JSON, such as the durations of olderThan, are read as
+// strings up to the next space or parenthesis. && binds tighter than || and parentheses group.
+// Like Expr.Or, || copies its right-hand side into each && of its left-hand
+// side, so a disjunction of long conjunctions can produce a large filter.
+func ParseExpression(expr string) (*Filter, error) {
+	p := &exprParser{src: expr}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return e.f, nil
+}
+
+type exprParser struct {
+	src string
+	pos int
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("expression: col %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && isExprSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// consume skips tok and reports whether it was next in the input
+func (p *exprParser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (Expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return x, err
+	}
+	for p.consume("||") {
+		y, err := p.parseAnd()
+		if err != nil {
+			return x, err
+		}
+		x = x.Or(y)
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseAnd() (Expr, error) {
+	x, err := p.parseTerm()
+	if err != nil {
+		return x, err
+	}
+	for p.consume("&&") {
+		y, err := p.parseTerm()
+		if err != nil {
+			return x, err
+		}
+		x = x.And(y)
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseTerm() (Expr, error) {
+	if p.consume("(") {
+		e, err := p.parseOr()
+		if err != nil {
+			return e, err
+		}
+		if !p.consume(")") {
+			return e, p.errorf("expected )")
+		}
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (Expr, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '$' {
+		return Expr{}, p.errorf("expected path")
+	}
+	start := p.pos
+	path, err := p.scanPath()
+	if err != nil {
+		return Expr{}, err
+	}
+	var jp jsonpath.JsonPath
+	quoted, _ := json.Marshal(path)
+	err = jp.UnmarshalJSON(quoted)
+	if err != nil {
+		p.pos = start
+		return Expr{}, p.errorf("invalid path %s: %s", path, err)
+	}
+	op, err := p.scanOperator()
+	if err != nil {
+		return Expr{}, err
+	}
+	if unaryOperator(op) {
+		return Expr{&Filter{Path: jp, Operator: op}}, nil
+	}
+	if op == opAnyElement || op == opAllElements || op == opNoElement {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '(' {
+			nested, err := p.parseTerm()
+			if err != nil {
+				return Expr{}, err
+			}
+			return Expr{&Filter{Path: jp, Operator: op, Value: nested.f}}, nil
+		}
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '$' && !isElementOperator(op) {
+		ref, err := p.scanRef()
+		if err != nil {
+			return Expr{}, err
+		}
+		return Expr{&Filter{Path: jp, Operator: op, Value: ref}}, nil
+	}
+	value, err := p.scanValue()
+	if err != nil {
+		return Expr{}, err
+	}
+	if isElementOperator(op) {
+		value, err = elementValue(op, value)
+		if err != nil {
+			p.pos = start
+			return Expr{}, p.errorf("%s", err)
+		}
+	}
+	return Expr{&Filter{Path: jp, Operator: op, Value: value}}, nil
+}
+
+// scanPath reads a JSONPath up to the operator following it. Brackets,
+// parentheses and quoted strings may contain spaces and operator characters.
+func (p *exprParser) scanPath() (string, error) {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(p.src[p.pos+1:], c)
+			if end < 0 {
+				return "", p.errorf("unterminated string in path")
+			}
+			p.pos += end + 2
+			continue
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || (c == ')' && depth > 0):
+			depth--
+		case depth == 0 && (isExprSpace(c) || strings.IndexByte("=!<>~)&|", c) >= 0):
+			return p.src[start:p.pos], nil
+		}
+		p.pos++
+	}
+	if depth != 0 {
+		return "", p.errorf("unbalanced brackets in path")
+	}
+	return p.src[start:p.pos], nil
+}
+
+// scanRef reads a JSONPath value referring to another value of the message
+func (p *exprParser) scanRef() (*PathRef, error) {
+	start := p.pos
+	path, err := p.scanPath()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := pathRefValue(map[string]interface{}{"path": path})
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("%s", err)
+	}
+	return ref, nil
+}
+
+// scanOperator reads a symbolic or named operator and returns its canonical name
+func (p *exprParser) scanOperator() (string, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte("=!<>~", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	if p.pos == start {
+		p.pos = start + len(exprWord(p.src[start:]))
+	}
+	tok := p.src[start:p.pos]
+	switch tok {
+	case "=~":
+		return opRegexMatch, nil
+	case "!~":
+		return opRegexNoMatch, nil
+	case "not":
+		p.skipSpace()
+		if exprWord(p.src[p.pos:]) == "in" {
+			p.pos += len("in")
+			return opNotIn, nil
+		}
+	}
+	if op, ok := canonicalOperator(tok); ok && tok != "" {
+		return op, nil
+	}
+	p.pos = start
+	return "", p.errorf("expected operator")
+}
+
+// scanValue reads a JSON value or a bare string
+func (p *exprParser) scanValue() (interface{}, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected value")
+	}
+	bare := false
+	switch p.src[p.pos] {
+	case '"':
+		end, err := skipString([]byte(p.src), p.pos)
+		if err != nil {
+			return nil, p.errorf("unterminated string")
+		}
+		p.pos = end
+	case '[', '{':
+		end, err := skipValue([]byte(p.src), p.pos)
+		if err != nil {
+			return nil, p.errorf("unterminated %c", p.src[p.pos])
+		}
+		p.pos = end
+	default:
+		bare = true
+		for p.pos < len(p.src) && !isExprSpace(p.src[p.pos]) && p.src[p.pos] != ')' &&
+			!strings.HasPrefix(p.src[p.pos:], "&&") && !strings.HasPrefix(p.src[p.pos:], "||") {
+			p.pos++
+		}
+		if p.pos == start {
+			return nil, p.errorf("expected value")
+		}
+	}
+	tok := p.src[start:p.pos]
+	if bare && !json.Valid([]byte(tok)) {
+		return tok, nil
+	}
+	v, err := decodeJSONValue([]byte(tok))
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid value %s: %s", tok, err)
+	}
+	return v, nil
+}
+
+func exprWord(s string) string {
+	i := 0
+	for i < len(s) && (s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z') {
+		i++
+	}
+	return s[:i]
+}
+
+func isExprSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,77 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpression(t *testing.T) {
+	msg := map[string]interface{}{
+		"amount":  150.0,
+		"country": "MX",
+		"vip":     true,
+		"name":    "alice smith",
+		"created": time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+		"a b":     "spaced",
+		"tags":    []interface{}{"a", "b"},
+		"limit":   100.0,
+		"regions": []interface{}{"MX", "US"},
+	}
+	cases := []struct {
+		expr string
+		pass bool
+	}{
+		{`$.amount > 100`, true},
+		{`$.amount>100&&$.vip==true`, true},
+		{`$.amount <= 100 || $.vip == true`, true},
+		{`$.amount > 100 && ($.country in ["US", "CA"] || $.vip == false)`, false},
+		{`$.amount > 100 && ($.country in ["US", "CA"] || $.vip == true)`, true},
+		{`$.country not in ["US", "CA"]`, true},
+		{`$.country notIn ["MX"]`, false},
+		{`$.name =~ "^alice"`, true},
+		{`$.name !~ ^bob`, true},
+		{`$.created olderThan 1h`, true},
+		{`$.created newer 1h`, false},
+		{`$["a b"] == "spaced"`, true},
+		{`($.amount gte 150) && ($.country ne "US")`, true},
+		{`$.vip exists && $.deleted notExists`, true},
+		{`$.vip isNull || ($.name isNotNull)`, true},
+		{`$.tags anyElement ($ == "b" || $ == "c") && $.tags all ($ ne "z")`, true},
+		{`$.tags count {"operator":">","value":2}`, false},
+		{`$.amount > $.limit`, true},
+		{`$.country in $.regions && $.name != $["a b"]`, true},
+	}
+	for _, c := range cases {
+		f, err := ParseExpression(c.expr)
+		if err != nil {
+			t.Errorf("%s: Failed to parse expression %s", c.expr, err)
+			continue
+		}
+		pass, err := f.Test(msg)
+		if err != nil {
+			t.Errorf("%s: Filter test failed %s", c.expr, err)
+			continue
+		}
+		if pass != c.pass {
+			t.Errorf("%s should pass: %t", c.expr, c.pass)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`amount > 1`,
+		`$.amount`,
+		`$.amount approx 1`,
+		`$.amount >`,
+		`($.amount > 1`,
+		`$.amount > 1 $.vip`,
+		`$.list in [1, 2`,
+	} {
+		_, err := ParseExpression(expr)
+		if err == nil {
+			t.Errorf("%q should fail to parse", expr)
+		}
+	}
+}
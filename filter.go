@@ -3,6 +3,7 @@ package filter
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
@@ -24,11 +25,29 @@ type Filter struct {
 	Value    interface{}        `json:"value"`
 	Operator string             `json:"operator"`
 	Requeue  bool               `json:"requeue"`
-	Or       *Filter            `json:"or"`
-	And      *Filter            `json:"and"`
-	Script   *ScriptFilter      `json:"script"`
+	// OnMissing is how operators other than the existence operators treat a
+	// path which does not resolve: MissingNull (the default), MissingFalse or
+	// MissingError
+	OnMissing string        `json:"onMissing,omitempty"`
+	Or        *Filter       `json:"or"`
+	And       *Filter       `json:"and"`
+	Script    *ScriptFilter `json:"script"`
 }
 
+// OnMissing modes
+const (
+	// MissingNull compares a missing value as null
+	MissingNull = "null"
+	// MissingFalse fails the comparison
+	MissingFalse = "false"
+	// MissingError fails Test with an error
+	MissingError = "error"
+)
+
+// errMissingPath is returned by operands when the path does not resolve and
+// the comparison fails without evaluating the operator
+var errMissingPath = fmt.Errorf("missing path")
+
 type ScriptFilter struct {
 	Interpreter string                 `json:"interpreter"`
 	Script      string                 `json:"script"`
@@ -58,114 +77,221 @@ func (f *Filter) Test(msg interface{}) (bool, error) {
 }
 
 func Test(f *Filter, msg interface{}) (bool, error) {
-	var val interface{}
-	var err error
-	var fVal interface{}
 	if f.Script != nil {
-		switch strings.ToLower(f.Script.Interpreter) {
-		case "javascript", "js", "es5":
-			vm := otto.New()
-			vm.Set("input", msg)
-			vm.Set("metadata", f.Script.Metadata)
-			var res otto.Value
-			if f.Script.ScriptFile != "" {
-				dat, err := os.ReadFile(f.Script.ScriptFile)
-				if err != nil {
-					return false, err
-				}
-				res, err = vm.Run(dat)
-				if err != nil {
-					return false, err
-				}
-				b, err := res.ToBoolean()
-				if err != nil {
-					return false, err
-				}
-				return b, nil
-			} else {
-				res, err = vm.Run(f.Script.Script)
-				if err != nil {
-					return false, err
-				}
-				b, err := res.ToBoolean()
-				if err != nil {
-					return false, err
-				}
-				return b, nil
+		return testScript(f, msg)
+	}
+	val, fVal, err := operands(f, msg)
+	if err == errMissingPath {
+		return testMissing(f), nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return testOperator(f, msg, val, fVal)
+}
+
+// testScript evaluates the script of f with the message as input
+func testScript(f *Filter, msg interface{}) (bool, error) {
+	switch strings.ToLower(f.Script.Interpreter) {
+	case "javascript", "js", "es5":
+		input, err := messageValue(msg)
+		if err != nil {
+			return false, err
+		}
+		vm := otto.New()
+		vm.Set("input", input)
+		vm.Set("metadata", f.Script.Metadata)
+		var res otto.Value
+		if f.Script.ScriptFile != "" {
+			dat, err := os.ReadFile(f.Script.ScriptFile)
+			if err != nil {
+				return false, err
 			}
-		default:
-			return false, fmt.Errorf("unsupported interpreter %s", f.Script.Interpreter)
+			res, err = vm.Run(dat)
+			if err != nil {
+				return false, err
+			}
+			b, err := res.ToBoolean()
+			if err != nil {
+				return false, err
+			}
+			return b, nil
+		} else {
+			res, err = vm.Run(f.Script.Script)
+			if err != nil {
+				return false, err
+			}
+			b, err := res.ToBoolean()
+			if err != nil {
+				return false, err
+			}
+			return b, nil
 		}
+	default:
+		return false, fmt.Errorf("unsupported interpreter %s", f.Script.Interpreter)
 	}
+}
+
+// operands returns the message value f compares and the interpolated or
+// referenced filter value, with numbers converted to float64
+func operands(f *Filter, msg interface{}) (val interface{}, fVal interface{}, err error) {
+	op, _ := canonicalOperator(f.Operator)
 	if f.Template != nil {
+		var data interface{}
+		data, err = messageValue(msg)
+		if err != nil {
+			return nil, nil, err
+		}
 		var b bytes.Buffer
-		err = f.Template.Execute(&b, msg)
+		err = f.Template.Execute(&b, data)
 		if err != nil {
-			return false, err
+			return nil, nil, err
 		}
 		val = b.String()
 	} else {
-		val, _ = jsonpath.GetPathValue(msg, f.Path)
-	}
-	if n, ok := val.(json.Number); ok {
-		val, err = n.Float64()
+		val, err = getPathValue(msg, f.Path)
 		if err != nil {
-			return false, fmt.Errorf("TypeAssertionError")
+			switch {
+			case !errors.Is(err, ErrNotFound):
+				return nil, nil, err
+			case isExistenceOperator(op) || f.OnMissing == MissingFalse:
+				return nil, nil, errMissingPath
+			case f.OnMissing == MissingError:
+				return nil, nil, fmt.Errorf("missing path %s: %w", f.Path.String(), err)
+			}
+			val, err = nil, nil
 		}
-	} else if n, ok := val.(int); ok {
-		val = float64(n)
-	} else if n, ok := val.(int64); ok {
-		val = float64(n)
 	}
-	if n, ok := f.Value.(json.Number); ok {
+	val, err = numberValue(val)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ref, ok := f.Value.(*PathRef); ok {
+		if unaryOperator(op) {
+			return val, nil, nil
+		}
+		fVal, err = ref.resolve(f, msg)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if n, ok := f.Value.(json.Number); ok {
 		fVal, err = n.Float64()
 		if err != nil {
-			return false, fmt.Errorf("TypeAssertionError")
+			return nil, nil, fmt.Errorf("TypeAssertionError")
 		}
 	} else if str, ok := f.Value.(string); ok {
-		fVal, err = template.Interpolate(msg, str)
+		var data interface{} = msg
+		if strings.Contains(str, "{{") {
+			data, err = messageValue(msg)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		fVal, err = template.Interpolate(data, str)
 		if err != nil {
-			return false, err
+			return nil, nil, err
 		}
 	} else {
 		fVal = f.Value
 	}
-	switch f.Operator {
-	case "!=", "<>", "ne", "doesn't equal", "not equal to":
-		return fVal != val, nil
-	case "=", "==", "eq", "equal", "equals":
-		return fVal == val, nil
-	case "in", "not in":
+	return val, fVal, nil
+}
+
+// numberValue converts the numbers of a message to float64
+func numberValue(v interface{}) (interface{}, error) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("TypeAssertionError")
+		}
+		return f, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	}
+	return v, nil
+}
+
+// numberValues converts the numbers in v and its elements to float64, so
+// differently written equal numbers such as 5 and 5.0 compare equal
+func numberValues(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = numberValues(e)
+		}
+		return s
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = numberValues(e)
+		}
+		return m
+	}
+	if n, err := numberValue(v); err == nil {
+		return n
+	}
+	return v
+}
+
+// testMissing is the result of f when its path does not resolve
+func testMissing(f *Filter) bool {
+	op, _ := canonicalOperator(f.Operator)
+	return op == opNotExists
+}
+
+// testOperator compares the operands of f with its operator
+func testOperator(f *Filter, msg interface{}, val interface{}, fVal interface{}) (bool, error) {
+	op, _ := canonicalOperator(f.Operator)
+	switch op {
+	case opExists:
+		return true, nil
+	case opNotExists:
+		return false, nil
+	case opIsNull:
+		return val == nil, nil
+	case opIsNotNull:
+		return val != nil, nil
+	case opNotEqual:
+		return !equalValues(fVal, val), nil
+	case opEqual:
+		return equalValues(fVal, val), nil
+	case opIn, opNotIn:
+		if fVal == nil {
+			// a referenced list which does not resolve holds nothing
+			return op == opNotIn, nil
+		}
 		rt := reflect.TypeOf(fVal)
 		switch rt.Kind() {
 		case reflect.Slice:
 			s := fVal.([]interface{})
 			for _, v := range s {
 				f2 := Filter{
-					Path:     f.Path,
-					Value:    v,
-					Operator: "==",
-					Requeue:  f.Requeue,
+					Path:      f.Path,
+					Value:     v,
+					Operator:  "==",
+					Requeue:   f.Requeue,
+					OnMissing: f.OnMissing,
 				}
 				ok, err := f2.Test(msg)
 				if ok || err != nil {
-					if f.Operator == "not in" {
+					if op == opNotIn {
 						return !ok, err
 					}
 					return ok, err
 				}
 			}
-			if f.Operator == "not in" {
+			if op == opNotIn {
 				return true, nil
 			}
 			return false, nil
 		default:
 			return false, fmt.Errorf("TypeAssertionError")
 		}
-	case "<", "lt", "less than",
-		">", "gt", "greater than",
-		">=", "ge", "gte", "greater than or equal to",
-		"<=", "le", "lte", "less than or equal to":
+	case opLessThan, opGreaterThan, opGreaterEqual, opLessEqual:
 		var fNum, vNum float64
 		var err error
 		if val == nil {
@@ -179,20 +305,19 @@ func Test(f *Filter, msg interface{}) (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		switch f.Operator {
-		case "<", "lt", "less than":
+		switch op {
+		case opLessThan:
 			return vNum < fNum, nil
-		case ">", "gt", "greater than":
+		case opGreaterThan:
 			return vNum > fNum, nil
-		case ">=", "ge", "gte", "greater than or equal to":
+		case opGreaterEqual:
 			return vNum >= fNum, nil
-		case "<=", "le", "lte", "less than or equal to":
+		case opLessEqual:
 			return vNum <= fNum, nil
 		default:
 			return false, fmt.Errorf("impossible condition")
 		}
-	case "olderThan", "older than", "older",
-		"newerThan", "newer than", "newer":
+	case opOlderThan, opNewerThan:
 		var rVal string
 		var ok bool
 		var err error
@@ -218,16 +343,15 @@ func Test(f *Filter, msg interface{}) (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		switch f.Operator {
-		case "olderThan", "older than", "older":
+		switch op {
+		case opOlderThan:
 			return time.Since(tVal) > time.Duration(dVal), nil
-		case "newerThan", "newer than", "newer":
+		case opNewerThan:
 			return time.Since(tVal) < time.Duration(dVal), nil
 		default:
 			return false, fmt.Errorf("impossible condition")
 		}
-	case "regexMatch", "regex match",
-		"regexNoMatch", "regex no match":
+	case opRegexMatch, opRegexNoMatch:
 		var re *regexp.Regexp
 		var err error
 		var rVal string
@@ -245,17 +369,99 @@ func Test(f *Filter, msg interface{}) (bool, error) {
 		if !ok {
 			return false, fmt.Errorf("TypeAssertionError")
 		}
-		switch f.Operator {
-		case "regexMatch", "regex match":
+		switch op {
+		case opRegexMatch:
 			return re.MatchString(tStr), nil
-		case "regexNoMatch", "regex no match":
+		case opRegexNoMatch:
 			return !re.MatchString(tStr), nil
 		default:
 			return false, fmt.Errorf("impossible condition")
 		}
+	case opContains, opNotContains, opStartsWith, opEndsWith, opEqualsIgnoreCase,
+		opContainsIgnoreCase, opNotContainsIgnoreCase, opStartsWithIgnoreCase, opEndsWithIgnoreCase:
+		if val == nil {
+			return false, nil
+		}
+		sub, ok := fVal.(string)
+		if !ok {
+			return false, fmt.Errorf("TypeAssertionError")
+		}
+		tStr, ok := val.(string)
+		if !ok {
+			return false, fmt.Errorf("TypeAssertionError")
+		}
+		return testString(op, tStr, sub), nil
+	case opGlob, opNotGlob, opGlobIgnoreCase, opNotGlobIgnoreCase:
+		if val == nil {
+			return false, nil
+		}
+		pattern, ok := fVal.(string)
+		if !ok {
+			return false, fmt.Errorf("TypeAssertionError")
+		}
+		tStr, ok := val.(string)
+		if !ok {
+			return false, fmt.Errorf("TypeAssertionError")
+		}
+		// patterns interpolated or referenced from the message are compiled
+		// every time
+		src, literal := f.Value.(string)
+		re, err := compileGlob(pattern, op == opGlobIgnoreCase || op == opNotGlobIgnoreCase, literal && !isTemplate(src))
+		if err != nil {
+			return false, err
+		}
+		if op == opNotGlob || op == opNotGlobIgnoreCase {
+			return !re.MatchString(tStr), nil
+		}
+		return re.MatchString(tStr), nil
+	case opAnyElement, opAllElements, opNoElement, opCountElements:
+		return testElements(f, op, val)
+	case opContainsAll, opContainsAny, opContainsNone, opSubsetOf, opEqualSet:
+		return testSet(op, msg, val, fVal)
+	case opLengthEq, opLengthNe, opLengthLt, opLengthLte, opLengthGt, opLengthGte, opIsEmpty, opIsNotEmpty:
+		return testLength(op, val, fVal)
+	case opIsType, opIsTimestamp, opIsNumeric:
+		return testType(op, val, fVal)
+	case opBefore, opAfter, opWithinLast, opWithinNext:
+		return testTime(op, val, fVal)
+	case opBetween, opNotBetween:
+		if val == nil {
+			return false, nil
+		}
+		r, err := parseRange(fVal)
+		if err != nil {
+			return false, err
+		}
+		r, err = r.interpolate(f, msg)
+		if err == errMissingPath {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		in, err := r.contains(val)
+		if err != nil {
+			return false, err
+		}
+		if op == opNotBetween {
+			return !in, nil
+		}
+		return in, nil
 	default:
-		return fVal == val, nil
+		return equalValues(fVal, val), nil
+	}
+}
+
+// equalValues compares a and b with ==, or element by element when either is
+// an array or object, which == can not compare. Numbers inside arrays and
+// objects compare by value.
+func equalValues(a, b interface{}) bool {
+	for _, v := range []interface{}{a, b} {
+		if t := reflect.TypeOf(v); t != nil && !t.Comparable() {
+			return reflect.DeepEqual(numberValues(a), numberValues(b))
+		}
 	}
+	return a == b
 }
 
 // func AndOr(bool, and *Filter, or *Filter) (bool, error) {
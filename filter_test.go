@@ -953,3 +953,113 @@ func TestRandomToJSON(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestExistenceOperators(t *testing.T) {
+	cases := []struct {
+		filter string
+		pass   []bool
+	}{
+		// against {"value":null}, {"value":"test"} and {}
+		{`{"path":"$.value","operator":"exists"}`, []bool{true, true, false}},
+		{`{"path":"$.value","operator":"notExists"}`, []bool{false, false, true}},
+		{`{"path":"$.value","operator":"is null"}`, []bool{true, false, false}},
+		{`{"path":"$.value","operator":"isNotNull"}`, []bool{false, true, false}},
+		{`{"path":"$.value","value":null}`, []bool{true, false, true}},
+		{`{"path":"$.value","value":null,"onMissing":"false"}`, []bool{true, false, false}},
+		{`{"path":"$.value","operator":"!=","value":"test","onMissing":"false"}`, []bool{true, false, false}},
+		{`{"path":"$.value","operator":"in","value":["test"],"onMissing":"false"}`, []bool{false, true, false}},
+	}
+	messages := []string{`{"value":null}`, `{"value":"test"}`, `{}`}
+	for _, c := range cases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		for i, m := range messages {
+			msg, err := decodeJSONMessage([]byte(m))
+			if err != nil {
+				t.Error("Failed to parse message", err)
+				return
+			}
+			for _, msg := range []interface{}{msg, &RawJSON{Data: []byte(m)}} {
+				pass, err := filter.Test(msg)
+				if err != nil {
+					t.Errorf("%s: Filter test failed %s", c.filter, err)
+					continue
+				}
+				if pass != c.pass[i] {
+					t.Errorf("%s against %s should pass: %t", c.filter, m, c.pass[i])
+				}
+			}
+		}
+	}
+}
+
+func TestOnMissingError(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.value","operator":">","value":1,"onMissing":"error"}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	pass, err := filter.Test(map[string]interface{}{"value": 2.0})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("present value should pass")
+	}
+	_, err = filter.Test(map[string]interface{}{})
+	if err == nil {
+		t.Error("missing path should fail with an error")
+		return
+	}
+	if !strings.HasPrefix(err.Error(), "missing path $.value: ") {
+		t.Errorf("Unexpected error %s", err)
+	}
+}
+
+// filterCase is a filter document and whether it passes the message it is
+// tested against
+type filterCase struct {
+	filter string
+	pass   bool
+}
+
+// testFilterCases parses the filter of every case and tests it against msg
+func testFilterCases(t *testing.T, msg interface{}, cases []filterCase) {
+	t.Helper()
+	for _, c := range cases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Errorf("%s: Failed to parse filter %s", c.filter, err)
+			continue
+		}
+		pass, err := filter.Test(msg)
+		if err != nil {
+			t.Errorf("%s: Filter test failed %s", c.filter, err)
+			continue
+		}
+		if pass != c.pass {
+			t.Errorf("%s should pass: %t", c.filter, c.pass)
+		}
+	}
+}
+
+// testFilterErrors parses every filter and checks that testing it against
+// msg fails
+func testFilterErrors(t *testing.T, msg interface{}, filters []string) {
+	t.Helper()
+	for _, f := range filters {
+		filter, err := ParseJSON([]byte(f))
+		if err != nil {
+			t.Errorf("%s: Failed to parse filter %s", f, err)
+			continue
+		}
+		_, err = filter.Test(msg)
+		if err == nil {
+			t.Errorf("%s should fail", f)
+		}
+	}
+}
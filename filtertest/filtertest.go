@@ -0,0 +1,236 @@
+// Package filtertest runs filter test fixtures.
+//
+// A fixture is a JSON or YAML file named *.test.json, *.test.yaml or
+// *.test.yml holding a filter and the expected outcome of evaluating it
+// against messages:
+//
+//	{
+//	  "filter": {"path": "$.amount", "operator": ">", "value": 100},
+//	  "cases": [
+//	    {"name": "large order", "message": {"amount": 150}, "expect": "pass"},
+//	    {"name": "small order", "message": {"amount": 50}, "expect": "fail"},
+//	    {"name": "no amount", "message": {"amount": "n/a"}, "expect": "error"}
+//	  ]
+//	}
+//
+// Instead of "filter", a fixture may give a filter expression in "expression"
+// or the path of a filter document relative to the fixture in "filterFile".
+// Messages are decoded like filters, with numbers as json.Number.
+package filtertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	filter "github.com/nickcarenza/go-filter"
+	"gopkg.in/yaml.v3"
+)
+
+// Expected outcomes of a Case
+const (
+	ExpectPass  = "pass"
+	ExpectFail  = "fail"
+	ExpectError = "error"
+)
+
+// Fixture is a filter and the cases it is tested against
+type Fixture struct {
+	// Path is the file the fixture was loaded from
+	Path       string         `json:"-"`
+	Filter     *filter.Filter `json:"filter,omitempty"`
+	Expression string         `json:"expression,omitempty"`
+	FilterFile string         `json:"filterFile,omitempty"`
+	Cases      []Case         `json:"cases"`
+}
+
+// Case is a message and the expected outcome of testing it
+type Case struct {
+	Name    string      `json:"name,omitempty"`
+	Message interface{} `json:"message"`
+	Expect  string      `json:"expect"`
+}
+
+// Result is the outcome of one case
+type Result struct {
+	Fixture *Fixture
+	Index   int
+	Case    Case
+	// Got is the outcome of the case: pass, fail or error
+	Got   string
+	Err   error
+	Trace *filter.Trace
+}
+
+// IsFixture reports whether name is named like a fixture file
+func IsFixture(name string) bool {
+	for _, suffix := range []string{".test.json", ".test.yaml", ".test.yml"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads a fixture file and resolves its filter
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		// decode YAML by way of JSON so messages get the same shapes
+		var doc interface{}
+		err = yaml.Unmarshal(data, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		data, err = json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	var fx Fixture
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&fx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	fx.Path = path
+	sources := 0
+	for _, set := range []bool{fx.Filter != nil, fx.Expression != "", fx.FilterFile != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("%s: exactly one of filter, expression or filterFile is required", path)
+	}
+	switch {
+	case fx.Expression != "":
+		fx.Filter, err = filter.ParseExpression(fx.Expression)
+	case fx.FilterFile != "":
+		fx.Filter, err = filter.ParseFile(filepath.Join(filepath.Dir(path), fx.FilterFile))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, c := range fx.Cases {
+		switch c.Expect {
+		case ExpectPass, ExpectFail, ExpectError:
+		default:
+			return nil, fmt.Errorf("%s: case %d: expect must be pass, fail or error, got %q", path, i+1, c.Expect)
+		}
+	}
+	return &fx, nil
+}
+
+// LoadDir loads every fixture in dir and its subdirectories, sorted by path
+func LoadDir(dir string) ([]*Fixture, error) {
+	var fixtures []*Fixture
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !IsFixture(d.Name()) {
+			return nil
+		}
+		fx, err := Load(path)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, fx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(fixtures, func(i, j int) bool {
+		return fixtures[i].Path < fixtures[j].Path
+	})
+	return fixtures, nil
+}
+
+// Run evaluates every case of the fixture
+func (fx *Fixture) Run() []Result {
+	results := make([]Result, len(fx.Cases))
+	for i, c := range fx.Cases {
+		r := Result{Fixture: fx, Index: i, Case: c}
+		r.Trace, r.Err = fx.Filter.Explain(c.Message)
+		switch {
+		case r.Err != nil:
+			r.Got = ExpectError
+		case r.Trace.Result:
+			r.Got = ExpectPass
+		default:
+			r.Got = ExpectFail
+		}
+		results[i] = r
+	}
+	return results
+}
+
+// Passed reports whether the case had the expected outcome
+func (r Result) Passed() bool {
+	return r.Got == r.Case.Expect
+}
+
+// Name identifies the case within its fixture
+func (r Result) Name() string {
+	if r.Case.Name != "" {
+		return fmt.Sprintf("case %d %q", r.Index+1, r.Case.Name)
+	}
+	return fmt.Sprintf("case %d", r.Index+1)
+}
+
+// String describes the outcome of the case followed by its evaluation trace
+func (r Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s: expected %s, got %s", r.Fixture.Path, r.Name(), r.Case.Expect, r.Got)
+	if r.Err != nil {
+		fmt.Fprintf(&b, ": %s", r.Err)
+	}
+	b.WriteByte('\n')
+	for _, l := range strings.Split(strings.TrimSuffix(r.Trace.String(), "\n"), "\n") {
+		fmt.Fprintf(&b, "    %s\n", l)
+	}
+	return b.String()
+}
+
+// RunFixtures runs every fixture in dir as a subtest of t, with a subtest for
+// every case. Failures are reported with the evaluation trace.
+func RunFixtures(t *testing.T, dir string) {
+	t.Helper()
+	fixtures, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal("Failed to load fixtures", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("No fixtures in %s", dir)
+	}
+	for _, fx := range fixtures {
+		name, err := filepath.Rel(dir, fx.Path)
+		if err != nil {
+			name = fx.Path
+		}
+		fx := fx
+		t.Run(name, func(t *testing.T) {
+			for _, r := range fx.Run() {
+				r := r
+				t.Run(r.Name(), func(t *testing.T) {
+					if !r.Passed() {
+						t.Error(r)
+					}
+				})
+			}
+		})
+	}
+}
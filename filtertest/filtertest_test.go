@@ -0,0 +1,57 @@
+package filtertest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunFixtures(t *testing.T) {
+	RunFixtures(t, "testdata/rules")
+}
+
+func TestFailingFixture(t *testing.T) {
+	fx, err := Load("testdata/failing/amount.test.json")
+	if err != nil {
+		t.Error("Failed to load fixture", err)
+		return
+	}
+	results := fx.Run()
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results, got %d", len(results))
+		return
+	}
+	if !results[0].Passed() || results[1].Passed() || results[2].Passed() {
+		t.Error("Unexpected outcomes", results)
+	}
+	expected := "testdata/failing/amount.test.json: case 2 \"boundary\": expected pass, got fail\n    FAIL $.amount gt 100 (actual 100)\n"
+	if results[1].String() != expected {
+		t.Errorf("Unexpected report %q", results[1])
+	}
+	if results[2].Got != ExpectError || !strings.Contains(results[2].String(), "got error: ") {
+		t.Errorf("Unexpected report %q", results[2])
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"none.test.json":    `{"cases":[]}`,
+		"both.test.json":    `{"filter":{"path":"$.a"},"expression":"$.a == 1","cases":[]}`,
+		"expect.test.json":  `{"expression":"$.a == 1","cases":[{"message":{},"expect":"maybe"}]}`,
+		"unknown.test.json": `{"expression":"$.a == 1","case":[]}`,
+		"missing.test.json": `{"filterFile":"missing.json","cases":[]}`,
+	} {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Error("Failed to write fixture", err)
+			return
+		}
+		_, err = Load(path)
+		if err == nil {
+			t.Errorf("%s should fail to load", name)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/nickcarenza/go-template"
+)
+
+// Canonicalize returns a copy of f with every operator alias replaced by its
+// canonical name and templates in string values reformatted the way Template
+// fields marshal. Unknown operators and the unused empty operator of scripts
+// are left as they are. The copy evaluates exactly like f.
+func Canonicalize(f *Filter) *Filter {
+	c := cloneFilter(f)
+	canonicalize(c)
+	return c
+}
+
+func canonicalize(f *Filter) {
+	walkFilters(f, func(node *Filter) {
+		if op, ok := canonicalOperator(node.Operator); ok && (node.Script == nil || node.Operator != "") {
+			node.Operator = op
+		}
+		if s, ok := node.Value.(string); ok && isTemplate(s) {
+			node.Value = formatTemplate(s)
+		}
+		if count, ok := node.Value.(*elementCount); ok {
+			count.Operator, _ = canonicalOperator(count.Operator)
+		}
+		canonicalize(nestedFilter(node))
+	})
+}
+
+// formatTemplate reformats the template src, leaving it unchanged if it does
+// not parse
+func formatTemplate(src string) string {
+	quoted, err := json.Marshal(src)
+	if err != nil {
+		return src
+	}
+	var t template.Template
+	err = json.Unmarshal(quoted, &t)
+	if err != nil {
+		return src
+	}
+	return templateSource(&t)
+}
+
+// Format returns the canonical JSON document of f: Canonicalize applied,
+// fields in struct order, null fields dropped along with false or empty
+// fields other than value, object keys within values sorted and two space
+// indentation.
+func Format(f *Filter) ([]byte, error) {
+	b, err := json.Marshal(Canonicalize(f))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	err = formatObject(&out, doc.(map[string]interface{}), reflect.TypeOf(Filter{}), "")
+	if err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// formatObject writes the fields of a struct of type t decoded into doc in
+// struct order, omitting empty fields
+func formatObject(out *bytes.Buffer, doc map[string]interface{}, t reflect.Type, indent string) error {
+	out.WriteByte('{')
+	first := true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		v, ok := doc[key]
+		// an empty or false value is compared, an absent one is null
+		if !ok || v == nil || (key != "value" && (v == "" || v == false)) {
+			continue
+		}
+		if !first {
+			out.WriteByte(',')
+		}
+		first = false
+		out.WriteString("\n" + indent + "  ")
+		err := formatValue(out, key, indent+"  ")
+		if err != nil {
+			return err
+		}
+		out.WriteString(": ")
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if key == "value" && t == reflect.TypeOf(Filter{}) {
+			// element operators nest a filter in their value
+			switch doc["operator"] {
+			case opAnyElement, opAllElements, opNoElement:
+				ft = t
+			case opCountElements:
+				ft = reflect.TypeOf(elementCount{})
+			}
+		}
+		if m, ok := v.(map[string]interface{}); ok && ft.Kind() == reflect.Struct && ft.PkgPath() == t.PkgPath() {
+			err = formatObject(out, m, ft, indent+"  ")
+		} else {
+			err = formatValue(out, v, indent+"  ")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !first {
+		out.WriteString("\n" + indent)
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+func formatValue(out *bytes.Buffer, v interface{}, indent string) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(indent, "  ")
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+	out.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+	return nil
+}
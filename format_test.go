@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"value": [3, {"b": null, "a": "<x>"}], "operator": "not in", "path": "$.value", "requeue": false, "template": null,
+		"or": {"path": "$.name", "operator": "greater than or equal to", "value": "{{ .min }}", "or": null, "and": {"path": "$.flag", "value": false}},
+		"and": {"script": {"interpreter": "js", "script": "true", "scriptFile": "", "metadata": null}}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	b, err := Format(filter)
+	if err != nil {
+		t.Error("Failed to format filter", err)
+		return
+	}
+	expected := `{
+  "path": "$.value",
+  "value": [
+    3,
+    {
+      "a": "<x>",
+      "b": null
+    }
+  ],
+  "operator": "notIn",
+  "or": {
+    "path": "$.name",
+    "value": "{{.min}}",
+    "operator": "gte",
+    "and": {
+      "path": "$.flag",
+      "value": false,
+      "operator": "eq"
+    }
+  },
+  "and": {
+    "script": {
+      "interpreter": "js",
+      "script": "true"
+    }
+  }
+}
+`
+	if string(b) != expected {
+		t.Errorf("Unexpected format\n%s", b)
+	}
+	parsed, err := ParseJSON(b)
+	if err != nil {
+		t.Error("Failed to parse formatted filter", err)
+		return
+	}
+	again, err := Format(parsed)
+	if err != nil {
+		t.Error("Failed to format filter", err)
+		return
+	}
+	if string(again) != string(b) {
+		t.Errorf("Format is not idempotent\n%s", again)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.value","operator":">","value":5,"or":{"path":"$.value","operator":"","value":"x"}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	c := Canonicalize(filter)
+	if c.Operator != "gt" || c.Or.Operator != "eq" {
+		t.Error("Operators should be canonical", c.Operator, c.Or.Operator)
+	}
+	if filter.Operator != ">" || filter.Or.Operator != "" {
+		t.Error("Canonicalize should not modify its argument")
+	}
+	for _, msg := range []string{`{"value":6}`, `{"value":4}`} {
+		m, err := decodeJSONMessage([]byte(msg))
+		if err != nil {
+			t.Error("Failed to parse message", err)
+			return
+		}
+		pass, err := filter.Test(m)
+		if err != nil {
+			t.Error("Filter test failed", err)
+			return
+		}
+		cpass, err := c.Test(m)
+		if err != nil {
+			t.Error("Filter test failed", err)
+			return
+		}
+		if pass != cpass {
+			t.Errorf("%s: canonical filter evaluates differently", msg)
+		}
+	}
+}
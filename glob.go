@@ -0,0 +1,150 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type globKey struct {
+	pattern string
+	fold    bool
+}
+
+// globCache holds the compiled form of every literal glob pattern
+var globCache sync.Map
+
+// compileGlob compiles a glob pattern, reusing the compiled pattern when cache
+// is set. Patterns interpolated from the message are not cached.
+func compileGlob(pattern string, fold bool, cache bool) (*regexp.Regexp, error) {
+	key := globKey{pattern, fold}
+	if re, ok := globCache.Load(key); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	expr, err := globRegexp(pattern, false)
+	if err != nil {
+		return nil, err
+	}
+	if fold {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	if cache {
+		globCache.Store(key, re)
+	}
+	return re, nil
+}
+
+// globRegexp translates a glob pattern to an anchored regular expression in
+// Go syntax, or in Lucene syntax when lucene is set. * and ? match within a
+// path segment, ** matches across segments and **/ matches zero or more whole
+// segments. [abc], [a-z] and [!abc] match one character of a class and a
+// backslash escapes the next character.
+func globRegexp(pattern string, lucene bool) (string, error) {
+	var b strings.Builder
+	group := "(?:"
+	if lucene {
+		group = "("
+	} else {
+		b.WriteString("(?s)^")
+	}
+	for i := 0; i < len(pattern); {
+		r, size := utf8.DecodeRuneInString(pattern[i:])
+		switch r {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**") {
+				i += 2
+				if strings.HasPrefix(pattern[i:], "/") {
+					b.WriteString(group + ".*/)?")
+					i++
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end, class, err := globClass(pattern, i, lucene)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(class)
+			i = end
+			continue
+		case '\\':
+			if i+1 >= len(pattern) {
+				return "", fmt.Errorf("invalid glob pattern %q: trailing backslash", pattern)
+			}
+			i += size
+			r, size = utf8.DecodeRuneInString(pattern[i:])
+			b.WriteString(globLiteral(r, lucene))
+		default:
+			b.WriteString(globLiteral(r, lucene))
+		}
+		i += size
+	}
+	if !lucene {
+		b.WriteString("$")
+	}
+	return b.String(), nil
+}
+
+// globClass translates the character class starting at pattern[start] and
+// returns the index following it
+func globClass(pattern string, start int, lucene bool) (int, string, error) {
+	var b strings.Builder
+	b.WriteByte('[')
+	i := start + 1
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		b.WriteString("^/")
+		i++
+	}
+	first := true
+	for i < len(pattern) {
+		r, size := utf8.DecodeRuneInString(pattern[i:])
+		if r == ']' && !first {
+			b.WriteByte(']')
+			return i + size, b.String(), nil
+		}
+		first = false
+		if r == '\\' && i+size < len(pattern) {
+			i += size
+			r, size = utf8.DecodeRuneInString(pattern[i:])
+		}
+		b.WriteString(globClassLiteral(r))
+		i += size
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi, hiSize := utf8.DecodeRuneInString(pattern[i+1:])
+			if hi < r {
+				return 0, "", fmt.Errorf("invalid glob pattern %q: invalid range %c-%c", pattern, r, hi)
+			}
+			b.WriteString("-" + globClassLiteral(hi))
+			i += 1 + hiSize
+		}
+	}
+	return 0, "", fmt.Errorf("invalid glob pattern %q: unterminated [", pattern)
+}
+
+func globLiteral(r rune, lucene bool) string {
+	if lucene {
+		if strings.ContainsRune(`.?+*|{}[]()"\#@&<>~`, r) {
+			return `\` + string(r)
+		}
+		return string(r)
+	}
+	return regexp.QuoteMeta(string(r))
+}
+
+func globClassLiteral(r rune) string {
+	if strings.ContainsRune(`\]^-[`, r) {
+		return `\` + string(r)
+	}
+	return string(r)
+}
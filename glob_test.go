@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	cases := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{"api-*.prod.*", "api-1.prod.example", true},
+		{"api-*.prod.*", "web-1.prod.example", false},
+		{"/v1/users/*/orders", "/v1/users/42/orders", true},
+		{"/v1/users/*/orders", "/v1/users/42/x/orders", false},
+		{"/v1/**/orders", "/v1/users/42/x/orders", true},
+		{"/v1/**/orders", "/v1/orders", true},
+		{"/v1/**", "/v1/users/42", true},
+		{"/v1/us?rs", "/v1/users", true},
+		{"/v1/us?rs", "/v1/us/rs", false},
+		{"host-[abc].local", "host-b.local", true},
+		{"host-[!abc].local", "host-b.local", false},
+		{"host-[a-c0-9]", "host-7", true},
+		{"host-[]]", "host-]", true},
+		{`price\*`, "price*", true},
+		{`price\*`, "prices", false},
+		{"a.b", "axb", false},
+		{"(x)+", "(x)+", true},
+		{"café*", "café au lait", true},
+	}
+	for _, c := range cases {
+		re, err := compileGlob(c.pattern, false, false)
+		if err != nil {
+			t.Errorf("%s: Failed to compile glob %s", c.pattern, err)
+			continue
+		}
+		if re.MatchString(c.value) != c.match {
+			t.Errorf("%s should match %s: %t", c.pattern, c.value, c.match)
+		}
+	}
+	for _, pattern := range []string{"host-[abc", `trailing\`, "[z-a]"} {
+		_, err := compileGlob(pattern, false, false)
+		if err == nil {
+			t.Errorf("%s should not compile", pattern)
+		}
+	}
+}
+
+func TestGlobFilter(t *testing.T) {
+	msg := map[string]interface{}{"host": "API-7.Prod.example.com", "env": "prod", "code": 200.0}
+	cases := []struct {
+		filter string
+		pass   bool
+	}{
+		{`{"path":"$.host","operator":"glob","value":"api-*.prod.*"}`, false},
+		{`{"path":"$.host","operator":"iglob","value":"api-*.prod.*"}`, true},
+		{`{"path":"$.host","operator":"not glob","value":"api-*.prod.*"}`, true},
+		{`{"path":"$.host","operator":"notIGlob","value":"api-*.prod.*"}`, false},
+		{`{"path":"$.host","operator":"iglob","value":"*.{{.env}}.*"}`, true},
+		{`{"path":"$.missing","operator":"glob","value":"*"}`, false},
+	}
+	for _, c := range cases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		pass, err := filter.Test(msg)
+		if err != nil {
+			t.Errorf("%s: Filter test failed %s", c.filter, err)
+			continue
+		}
+		if pass != c.pass {
+			t.Errorf("%s should pass: %t", c.filter, c.pass)
+		}
+	}
+	filter, err := ParseJSON([]byte(`{"path":"$.code","operator":"glob","value":"2*"}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	_, err = filter.Test(msg)
+	if err == nil {
+		t.Error("glob should fail on numbers")
+	}
+}
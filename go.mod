@@ -5,12 +5,19 @@ go 1.22
 toolchain go1.22.2
 
 require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/fxamacker/cbor/v2 v2.7.0
 	github.com/nickcarenza/go-template v1.11.0
 	github.com/the-control-group/go-jsonpath v1.1.1
 	github.com/the-control-group/go-timeutils v1.0.4
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/text v0.6.0 // indirect
 	gopkg.in/sourcemap.v1 v1.0.5 // indirect
 )
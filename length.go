@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// valueLength is the number of runes of a string, elements of an array or
+// keys of an object. Null has length 0.
+func valueLength(val interface{}) (int, error) {
+	switch v := val.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return utf8.RuneCountInString(v), nil
+	case []interface{}:
+		return len(v), nil
+	case map[string]interface{}:
+		return len(v), nil
+	default:
+		return 0, fmt.Errorf("TypeAssertionError")
+	}
+}
+
+// testLength applies the length operator op to the message value val and
+// the filter value fVal
+func testLength(op string, val interface{}, fVal interface{}) (bool, error) {
+	n, err := valueLength(val)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case opIsEmpty:
+		return n == 0, nil
+	case opIsNotEmpty:
+		return n > 0, nil
+	}
+	if val == nil {
+		return false, nil
+	}
+	want, err := interfaceToFloat64(fVal)
+	if err != nil {
+		return false, err
+	}
+	length := float64(n)
+	switch op {
+	case opLengthEq:
+		return length == want, nil
+	case opLengthNe:
+		return length != want, nil
+	case opLengthLt:
+		return length < want, nil
+	case opLengthLte:
+		return length <= want, nil
+	case opLengthGt:
+		return length > want, nil
+	case opLengthGte:
+		return length >= want, nil
+	default:
+		return false, fmt.Errorf("impossible condition")
+	}
+}
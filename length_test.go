@@ -0,0 +1,35 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestLengthOperators(t *testing.T) {
+	msg, err := decodeJSONMessage([]byte(`{"comment":"héllo","items":[1,2,3],"meta":{"a":1},"blank":"","none":[],"null":null,"max":5,"qty":2}`))
+	if err != nil {
+		t.Error("Failed to parse message", err)
+		return
+	}
+	testFilterCases(t, msg, []filterCase{
+		{`{"path":"$.comment","operator":"lengthEq","value":5}`, true},
+		{`{"path":"$.comment","operator":"lengthLt","value":500}`, true},
+		{`{"path":"$.comment","operator":"length <=","value":"{{.max}}"}`, true},
+		{`{"path":"$.items","operator":"lengthGte","value":1}`, true},
+		{`{"path":"$.items","operator":"lengthGt","value":3}`, false},
+		{`{"path":"$.items","operator":"lengthNe","value":3}`, false},
+		{`{"path":"$.meta","operator":"lengthEq","value":1}`, true},
+		{`{"path":"$.missing","operator":"lengthLt","value":10}`, false},
+		{`{"path":"$.blank","operator":"isEmpty"}`, true},
+		{`{"path":"$.none","operator":"is empty"}`, true},
+		{`{"path":"$.null","operator":"isEmpty"}`, true},
+		{`{"path":"$.missing","operator":"isEmpty"}`, true},
+		{`{"path":"$.missing","operator":"isEmpty","onMissing":"false"}`, false},
+		{`{"path":"$.items","operator":"isNotEmpty"}`, true},
+		{`{"path":"$.meta","operator":"isEmpty"}`, false},
+	})
+	testFilterErrors(t, msg, []string{
+		`{"path":"$.qty","operator":"lengthEq","value":1}`,
+		`{"path":"$.qty","operator":"isEmpty"}`,
+		`{"path":"$.items","operator":"lengthEq","value":"three"}`,
+	})
+}
@@ -0,0 +1,352 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/the-control-group/go-timeutils"
+)
+
+// Severity ranks a Diagnostic
+type Severity string
+
+// Diagnostic severities
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Diagnostic codes reported by Lint
+const (
+	LintMissingPath         = "missing-path"
+	LintUnknownOperator     = "unknown-operator"
+	LintTypeMismatch        = "type-mismatch"
+	LintRegexInvalid        = "regex-invalid"
+	LintRegexUnanchored     = "regex-unanchored"
+	LintListNotList         = "list-not-list"
+	LintListDuplicate       = "list-duplicate"
+	LintListMixedTypes      = "list-mixed-types"
+	LintDurationInvalid     = "duration-invalid"
+	LintOrUnreachable       = "or-unreachable"
+	LintTemplateSideEffects = "template-side-effects"
+	LintOnMissingInvalid    = "on-missing-invalid"
+	LintValueIgnored        = "value-ignored"
+	LintValueNotString      = "value-not-string"
+	LintGlobInvalid         = "glob-invalid"
+	LintRangeInvalid        = "range-invalid"
+	LintRangeEmpty          = "range-empty"
+	LintElementInvalid      = "element-invalid"
+	LintTypeInvalid         = "type-invalid"
+	LintTimestampInvalid    = "timestamp-invalid"
+)
+
+// Diagnostic is a likely mistake found by Lint. Location is a JSON Pointer to
+// the offending part of the filter document, "" for the root filter.
+type Diagnostic struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Location string   `json:"location"`
+	Message  string   `json:"message"`
+}
+
+// String formats the diagnostic as location: severity: message (code), with
+// the location as a URI fragment
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("#%s: %s: %s (%s)", d.Location, d.Severity, d.Message, d.Code)
+}
+
+// Lint reports likely mistakes in f which Test does not reject. Samples are
+// optional messages used to check values against the types the paths they are
+// compared with usually hold.
+func Lint(f *Filter, samples ...interface{}) []Diagnostic {
+	l := &linter{samples: samples}
+	l.lint(f, "")
+	return l.diagnostics
+}
+
+type linter struct {
+	samples     []interface{}
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(code string, severity Severity, location string, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{code, severity, location, fmt.Sprintf(format, args...)})
+}
+
+// lint checks f and the Or chain starting at f
+func (l *linter) lint(f *Filter, location string) {
+	l.lintOrChain(f, location)
+	for ; f != nil; f, location = f.Or, location+"/or" {
+		l.lintNode(f, location)
+		if f.And != nil {
+			l.lint(f.And, location+"/and")
+		}
+	}
+}
+
+func (l *linter) lintNode(f *Filter, location string) {
+	if f.Template != nil {
+		l.lintTemplate(templateSource(f.Template), location+"/template")
+	}
+	if s, ok := f.Value.(string); ok {
+		l.lintTemplate(s, location+"/value")
+	}
+	if f.Script != nil {
+		return
+	}
+	if f.Template == nil && f.Path.Path == nil {
+		l.report(LintMissingPath, SeverityError, location, "filter has no path, template or script")
+	}
+	op, ok := canonicalOperator(f.Operator)
+	if !ok {
+		l.report(LintUnknownOperator, SeverityError, location+"/operator", "unknown operator %q is evaluated as eq", f.Operator)
+		return
+	}
+	switch f.OnMissing {
+	case "", MissingNull, MissingFalse, MissingError:
+	default:
+		l.report(LintOnMissingInvalid, SeverityError, location+"/onMissing", "unknown onMissing %q is treated as null, expected null, false or error", f.OnMissing)
+	}
+	if unaryOperator(op) && f.Value != nil {
+		l.report(LintValueIgnored, SeverityWarning, location+"/value", "%s ignores the value", op)
+	}
+	if _, ok := f.Value.(*PathRef); ok {
+		// the shape of a referenced value is only known at evaluation
+		return
+	}
+	switch op {
+	case opEqual, opNotEqual:
+		l.lintValueType(f, f.Value, location+"/value")
+	case opIn, opNotIn, opContainsAll, opContainsAny, opContainsNone, opSubsetOf, opEqualSet:
+		l.lintList(f, location+"/value")
+	case opOlderThan, opNewerThan, opWithinLast, opWithinNext:
+		s, ok := f.Value.(string)
+		if !ok {
+			l.report(LintDurationInvalid, SeverityError, location+"/value", "%s expects a duration string", op)
+		} else if !isTemplate(s) && !durationRegexp.MatchString(s) {
+			// ParseApproxBigDuration reads anything it does not understand as 0
+			l.report(LintDurationInvalid, SeverityError, location+"/value", "invalid duration %q, expected a duration like 30s, 15m, 24h or 7d", s)
+		}
+	case opBefore, opAfter:
+		s, ok := f.Value.(string)
+		if !ok {
+			l.report(LintTimestampInvalid, SeverityError, location+"/value", "%s expects a timestamp string", op)
+		} else if _, err := timeutils.ParseAny(s); err != nil && !isTemplate(s) {
+			l.report(LintTimestampInvalid, SeverityError, location+"/value", "invalid timestamp %q, expected a date or time like 2024-03-01 or 2024-03-01T12:00:00Z", s)
+		}
+	case opRegexMatch, opRegexNoMatch:
+		s, ok := f.Value.(string)
+		if !ok {
+			l.report(LintRegexInvalid, SeverityError, location+"/value", "%s expects a pattern string", op)
+		} else if !isTemplate(s) {
+			_, err := regexp.Compile(s)
+			if err != nil {
+				l.report(LintRegexInvalid, SeverityError, location+"/value", "invalid pattern: %s", err)
+			} else if !strings.HasPrefix(s, "^") && !strings.HasPrefix(s, `\A`) &&
+				!strings.HasSuffix(s, "$") && !strings.HasSuffix(s, `\z`) {
+				l.report(LintRegexUnanchored, SeverityWarning, location+"/value", "pattern %q is not anchored and matches anywhere in the value", s)
+			}
+		}
+	case opGlob, opNotGlob, opGlobIgnoreCase, opNotGlobIgnoreCase:
+		s, ok := f.Value.(string)
+		if !ok {
+			l.report(LintGlobInvalid, SeverityError, location+"/value", "%s expects a pattern string", op)
+		} else if !isTemplate(s) {
+			_, err := globRegexp(s, false)
+			if err != nil {
+				l.report(LintGlobInvalid, SeverityError, location+"/value", "%s", err)
+			}
+		}
+	case opBetween, opNotBetween:
+		l.lintRange(f.Value, location+"/value")
+	case opAnyElement, opAllElements, opNoElement, opCountElements:
+		l.lintElements(f, op, location+"/value")
+	case opIsType:
+		_, err := typeNames(f.Value)
+		if err != nil {
+			l.report(LintTypeInvalid, SeverityError, location+"/value", "isType expects a type name or a list of them: %s", err)
+		}
+	default:
+		if _, ok := f.Value.(string); isStringOperator(op) && !ok {
+			l.report(LintValueNotString, SeverityError, location+"/value", "%s expects a string", op)
+		}
+	}
+}
+
+// lintElements reports invalid element operator values and lints the nested
+// filter. Samples are not used for it as its paths are relative to elements.
+func (l *linter) lintElements(f *Filter, op string, location string) {
+	v, err := elementValue(op, f.Value)
+	if err != nil {
+		l.report(LintElementInvalid, SeverityError, location, "%s", err)
+		return
+	}
+	if count, ok := v.(*elementCount); ok {
+		if count.Filter == nil {
+			return
+		}
+		location += "/filter"
+	}
+	nested := &linter{}
+	nested.lint(nestedFilter(&Filter{Value: v}), location)
+	l.diagnostics = append(l.diagnostics, nested.diagnostics...)
+}
+
+// lintRange reports malformed ranges and numeric ranges nothing can lie in
+func (l *linter) lintRange(v interface{}, location string) {
+	r, err := parseRange(v)
+	if err != nil {
+		l.report(LintRangeInvalid, SeverityError, location, "%s", err)
+		return
+	}
+	min, errMin := interfaceToFloat64(r.Min)
+	max, errMax := interfaceToFloat64(r.Max)
+	if errMin == nil && errMax == nil && (min > max || min == max && !(r.MinInclusive && r.MaxInclusive)) {
+		l.report(LintRangeEmpty, SeverityWarning, location, "range from %s to %s is empty", traceValue(r.Min), traceValue(r.Max))
+	}
+}
+
+func (l *linter) lintList(f *Filter, location string) {
+	list, ok := f.Value.([]interface{})
+	if !ok {
+		l.report(LintListNotList, SeverityError, location, "%s expects a list", f.Operator)
+		return
+	}
+	types := map[string]bool{}
+	for i, v := range list {
+		types[valueKind(v)] = true
+		for j := 0; j < i; j++ {
+			if reflect.DeepEqual(list[j], v) {
+				l.report(LintListDuplicate, SeverityWarning, fmt.Sprintf("%s/%d", location, i), "%s is a duplicate of item %d", traceValue(v), j)
+				break
+			}
+		}
+		l.lintValueType(f, v, fmt.Sprintf("%s/%d", location, i))
+	}
+	if len(types) > 1 {
+		kinds := make([]string, 0, len(types))
+		for k := range types {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		l.report(LintListMixedTypes, SeverityWarning, location, "list mixes %s values, which only equal values of the same type", strings.Join(kinds, " and "))
+	}
+}
+
+// lintValueType warns when v is a number and the path usually holds strings,
+// or v is a numeric string and the path usually holds numbers: "1" does not
+// equal 1
+func (l *linter) lintValueType(f *Filter, v interface{}, location string) {
+	if len(l.samples) == 0 || f.Template != nil {
+		return
+	}
+	kind := valueKind(v)
+	if kind != "number" && kind != "string" {
+		return
+	}
+	if s, ok := v.(string); ok && (isTemplate(s) || !isNumeric(s)) {
+		return
+	}
+	counts := map[string]int{}
+	for _, msg := range l.samples {
+		val, err := getPathValue(msg, f.Path)
+		if err == nil {
+			counts[valueKind(val)]++
+		}
+	}
+	other := "string"
+	if kind == "string" {
+		other = "number"
+	}
+	if counts[other] > counts[kind] {
+		l.report(LintTypeMismatch, SeverityWarning, location, "%s %s is compared with %s, which holds a %s in %d of %d samples", kind, traceValue(v), f.Path.String(), other, counts[other], len(l.samples))
+	}
+}
+
+// lintOrChain reports Or branches of the chain starting at f whose condition
+// is redundant, because it repeats an earlier condition which has failed when
+// it is evaluated. Conditions using templates are skipped, as templates may
+// not give the same result twice.
+func (l *linter) lintOrChain(f *Filter, location string) {
+	var seen []string
+	for ; f != nil && f.Or != nil; f, location = f.Or, location+"/or" {
+		if !usesTemplate(f) {
+			seen = append(seen, conditionKey(f))
+		}
+		if usesTemplate(f.Or) {
+			continue
+		}
+		key := conditionKey(f.Or)
+		for _, s := range seen {
+			if s == key {
+				l.report(LintOrUnreachable, SeverityWarning, location+"/or", "or repeats an earlier condition, which is redundant as it has already failed")
+				break
+			}
+		}
+	}
+}
+
+// usesTemplate reports whether the condition of f executes a template
+func usesTemplate(f *Filter) bool {
+	s, _ := f.Value.(string)
+	return f.Template != nil || isTemplate(s)
+}
+
+var durationRegexp = regexp.MustCompile(durationPattern)
+
+var templateSideEffect = regexp.MustCompile(`(^|[\s(|])(http|env)(\s|\)|$)`)
+var templateAction = regexp.MustCompile(`{{(.*?)}}`)
+
+// lintTemplate reports templates calling functions which reach outside the
+// message
+func (l *linter) lintTemplate(src string, location string) {
+	for _, m := range templateAction.FindAllStringSubmatch(src, -1) {
+		if call := templateSideEffect.FindStringSubmatch(m[1]); call != nil {
+			l.report(LintTemplateSideEffects, SeverityWarning, location, "template calls %s, which makes evaluation depend on the environment and slow", call[2])
+		}
+	}
+}
+
+// conditionKey identifies the condition of f, without its Or and And clauses
+func conditionKey(f *Filter) string {
+	c := *f
+	c.Or, c.And = nil, nil
+	c.Operator, _ = canonicalOperator(c.Operator)
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+// valueKind names the JSON type of v
+func valueKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number, float64, float32, int, int64, int32, uint, uint64, uint32:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
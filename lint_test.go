@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	cases := []struct {
+		filter   string
+		expected []Diagnostic
+	}{
+		{`{"path":"$.value","operator":"in","value":[1,2,3]}`, nil},
+		{`{"path":"$.value","operator":"approx","value":1}`, []Diagnostic{
+			{LintUnknownOperator, SeverityError, "/operator", `unknown operator "approx" is evaluated as eq`},
+		}},
+		{`{"path":"$.name","operator":"regexMatch","value":"smith"}`, []Diagnostic{
+			{LintRegexUnanchored, SeverityWarning, "/value", `pattern "smith" is not anchored and matches anywhere in the value`},
+		}},
+		{`{"path":"$.name","operator":"regexMatch","value":"^smith"}`, nil},
+		{`{"path":"$.name","operator":"regex no match","value":"(smith"}`, []Diagnostic{
+			{LintRegexInvalid, SeverityError, "/value", "invalid pattern: error parsing regexp: missing closing ): `(smith`"},
+		}},
+		{`{"path":"$.value","operator":"in","value":[1,"2",1]}`, []Diagnostic{
+			{LintListDuplicate, SeverityWarning, "/value/2", "1 is a duplicate of item 0"},
+			{LintListMixedTypes, SeverityWarning, "/value", "list mixes number and string values, which only equal values of the same type"},
+		}},
+		{`{"path":"$.value","operator":"not in","value":"1"}`, []Diagnostic{
+			{LintListNotList, SeverityError, "/value", "not in expects a list"},
+		}},
+		{`{"path":"$.created","operator":"olderThan","value":"soon","and":{"path":"$.updated","operator":"newer","value":5}}`, []Diagnostic{
+			{LintDurationInvalid, SeverityError, "/value", `invalid duration "soon", expected a duration like 30s, 15m, 24h or 7d`},
+			{LintDurationInvalid, SeverityError, "/and/value", "newerThan expects a duration string"},
+		}},
+		{`{"path":"$.a","value":1,"or":{"path":"$.b","value":2,"or":{"path":"$.a","operator":"==","value":1}}}`, []Diagnostic{
+			{LintOrUnreachable, SeverityWarning, "/or/or", "or repeats an earlier condition, which is redundant as it has already failed"},
+		}},
+		{`{"path":"$.a","value":"{{ randomInt 0 2 }}","or":{"path":"$.a","value":"{{ randomInt 0 2 }}"}}`, nil},
+		{`{"value":null,"or":{"path":"$.b","value":2}}`, []Diagnostic{
+			{LintMissingPath, SeverityError, "", "filter has no path, template or script"},
+		}},
+		{`{"template":"{{ env \"REGION\" }}","value":"us","and":{"path":"$.a","value":"{{http \"GET\" .url}}"}}`, []Diagnostic{
+			{LintTemplateSideEffects, SeverityWarning, "/template", "template calls env, which makes evaluation depend on the environment and slow"},
+			{LintTemplateSideEffects, SeverityWarning, "/and/value", "template calls http, which makes evaluation depend on the environment and slow"},
+		}},
+		{`{"path":"$.a","operator":"exists","value":true,"and":{"path":"$.b","value":1,"onMissing":"skip"}}`, []Diagnostic{
+			{LintValueIgnored, SeverityWarning, "/value", "exists ignores the value"},
+			{LintOnMissingInvalid, SeverityError, "/and/onMissing", `unknown onMissing "skip" is treated as null, expected null, false or error`},
+		}},
+		{`{"path":"$.a","operator":"startsWith","value":5}`, []Diagnostic{
+			{LintValueNotString, SeverityError, "/value", "startsWith expects a string"},
+		}},
+		{`{"path":"$.a","operator":"glob","value":"host-[ab"}`, []Diagnostic{
+			{LintGlobInvalid, SeverityError, "/value", `invalid glob pattern "host-[ab": unterminated [`},
+		}},
+		{`{"path":"$.a","operator":"between","value":[20,10],"and":{"path":"$.b","operator":"between","value":[1,2,3]}}`, []Diagnostic{
+			{LintRangeEmpty, SeverityWarning, "/value", "range from 20 to 10 is empty"},
+			{LintRangeInvalid, SeverityError, "/and/value", "range expects [min, max], got 3 items"},
+		}},
+		{`{"path":"$.a","operator":"any","value":{"path":"$.b","operator":"approx","value":1},"and":{"path":"$.c","operator":"count","value":{"filter":{"value":1},"operator":"eq","value":1}}}`, []Diagnostic{
+			{LintUnknownOperator, SeverityError, "/value/operator", `unknown operator "approx" is evaluated as eq`},
+			{LintMissingPath, SeverityError, "/and/value/filter", "filter has no path, template or script"},
+		}},
+		{`{"path":"$.a","operator":"isType","value":"int"}`, []Diagnostic{
+			{LintTypeInvalid, SeverityError, "/value", `isType expects a type name or a list of them: unknown type "int", expected one of [string number integer boolean array object null]`},
+		}},
+		{`{"path":"$.a","operator":"before","value":"tomorrow","and":{"path":"$.b","operator":"withinNext","value":"soon","and":{"path":"$.c","operator":"after","value":{"path":"$.d"}}}}`, []Diagnostic{
+			{LintTimestampInvalid, SeverityError, "/value", `invalid timestamp "tomorrow", expected a date or time like 2024-03-01 or 2024-03-01T12:00:00Z`},
+			{LintDurationInvalid, SeverityError, "/and/value", `invalid duration "soon", expected a duration like 30s, 15m, 24h or 7d`},
+		}},
+	}
+	for _, c := range cases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		diagnostics := Lint(filter)
+		if len(diagnostics) != len(c.expected) {
+			t.Errorf("%s: expected %d diagnostics, got %v", c.filter, len(c.expected), diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d != c.expected[i] {
+				t.Errorf("%s: expected %s, got %s", c.filter, c.expected[i], d)
+			}
+		}
+	}
+}
+
+func TestLintSamples(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.value","operator":"in","value":[1,2,3],"or":{"path":"$.count","value":"5"}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var samples []interface{}
+	for _, s := range []string{`{"value":"1","count":5}`, `{"value":"2","count":1}`, `{"value":3}`} {
+		msg, err := decodeJSONMessage([]byte(s))
+		if err != nil {
+			t.Error("Failed to parse message", err)
+			return
+		}
+		samples = append(samples, msg)
+	}
+	diagnostics := Lint(filter, samples...)
+	expected := []string{
+		`#/value/0: warning: number 1 is compared with $.value, which holds a string in 2 of 3 samples (type-mismatch)`,
+		`#/value/1: warning: number 2 is compared with $.value, which holds a string in 2 of 3 samples (type-mismatch)`,
+		`#/value/2: warning: number 3 is compared with $.value, which holds a string in 2 of 3 samples (type-mismatch)`,
+		`#/or/value: warning: string "5" is compared with $.count, which holds a number in 2 of 3 samples (type-mismatch)`,
+	}
+	if len(diagnostics) != len(expected) {
+		t.Errorf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+		return
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], d)
+		}
+	}
+}
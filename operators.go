@@ -0,0 +1,170 @@
+package filter
+
+// Canonical operator names. Every spelling accepted in Filter.Operator is an
+// alias of exactly one of these.
+const (
+	opEqual        = "eq"
+	opNotEqual     = "ne"
+	opIn           = "in"
+	opNotIn        = "notIn"
+	opLessThan     = "lt"
+	opGreaterThan  = "gt"
+	opGreaterEqual = "gte"
+	opLessEqual    = "lte"
+	opOlderThan    = "olderThan"
+	opNewerThan    = "newerThan"
+	opRegexMatch   = "regexMatch"
+	opRegexNoMatch = "regexNoMatch"
+	opExists       = "exists"
+	opNotExists    = "notExists"
+	opIsNull       = "isNull"
+	opIsNotNull    = "isNotNull"
+
+	opContains              = "contains"
+	opNotContains           = "notContains"
+	opStartsWith            = "startsWith"
+	opEndsWith              = "endsWith"
+	opEqualsIgnoreCase      = "equalsIgnoreCase"
+	opContainsIgnoreCase    = "containsIgnoreCase"
+	opNotContainsIgnoreCase = "notContainsIgnoreCase"
+	opStartsWithIgnoreCase  = "startsWithIgnoreCase"
+	opEndsWithIgnoreCase    = "endsWithIgnoreCase"
+
+	opGlob              = "glob"
+	opNotGlob           = "notGlob"
+	opGlobIgnoreCase    = "globIgnoreCase"
+	opNotGlobIgnoreCase = "notGlobIgnoreCase"
+
+	opBetween    = "between"
+	opNotBetween = "notBetween"
+
+	opAnyElement    = "anyElement"
+	opAllElements   = "allElements"
+	opNoElement     = "noElement"
+	opCountElements = "countElements"
+
+	opContainsAll  = "containsAll"
+	opContainsAny  = "containsAny"
+	opContainsNone = "containsNone"
+	opSubsetOf     = "subsetOf"
+	opEqualSet     = "equalSet"
+
+	opLengthEq   = "lengthEq"
+	opLengthNe   = "lengthNe"
+	opLengthLt   = "lengthLt"
+	opLengthLte  = "lengthLte"
+	opLengthGt   = "lengthGt"
+	opLengthGte  = "lengthGte"
+	opIsEmpty    = "isEmpty"
+	opIsNotEmpty = "isNotEmpty"
+
+	opIsType      = "isType"
+	opIsTimestamp = "isTimestamp"
+	opIsNumeric   = "isNumeric"
+
+	opBefore     = "before"
+	opAfter      = "after"
+	opWithinLast = "withinLast"
+	opWithinNext = "withinNext"
+)
+
+type operator struct {
+	Name    string
+	Aliases []string
+	// Value is the JSON Schema of the filter value, nil for any value
+	Value map[string]interface{}
+	// Unary operators ignore the filter value
+	Unary bool
+}
+
+// operators is the registry of every operator understood by Test
+var operators = []operator{
+	{opEqual, []string{"", "=", "==", "equal", "equals"}, nil, false},
+	{opNotEqual, []string{"!=", "<>", "doesn't equal", "not equal to"}, nil, false},
+	{opIn, nil, schemaList, false},
+	{opNotIn, []string{"not in"}, schemaList, false},
+	{opLessThan, []string{"<", "less than"}, schemaNumber, false},
+	{opGreaterThan, []string{">", "greater than"}, schemaNumber, false},
+	{opGreaterEqual, []string{">=", "ge", "greater than or equal to"}, schemaNumber, false},
+	{opLessEqual, []string{"<=", "le", "less than or equal to"}, schemaNumber, false},
+	{opOlderThan, []string{"older than", "older"}, schemaDuration, false},
+	{opNewerThan, []string{"newer than", "newer"}, schemaDuration, false},
+	{opRegexMatch, []string{"regex match"}, schemaRegexp, false},
+	{opRegexNoMatch, []string{"regex no match"}, schemaRegexp, false},
+	{opExists, []string{"exist"}, nil, true},
+	{opNotExists, []string{"not exists", "missing"}, nil, true},
+	{opIsNull, []string{"is null"}, nil, true},
+	{opIsNotNull, []string{"is not null"}, nil, true},
+	{opContains, []string{"contain"}, schemaString, false},
+	{opNotContains, []string{"not contains", "doesn't contain"}, schemaString, false},
+	{opStartsWith, []string{"starts with", "prefix"}, schemaString, false},
+	{opEndsWith, []string{"ends with", "suffix"}, schemaString, false},
+	{opEqualsIgnoreCase, []string{"equals ignore case", "iequals"}, schemaString, false},
+	{opContainsIgnoreCase, []string{"contains ignore case", "icontains"}, schemaString, false},
+	{opNotContainsIgnoreCase, []string{"not contains ignore case", "notIContains"}, schemaString, false},
+	{opStartsWithIgnoreCase, []string{"starts with ignore case", "istartsWith"}, schemaString, false},
+	{opEndsWithIgnoreCase, []string{"ends with ignore case", "iendsWith"}, schemaString, false},
+	{opGlob, nil, schemaGlob, false},
+	{opNotGlob, []string{"not glob"}, schemaGlob, false},
+	{opGlobIgnoreCase, []string{"glob ignore case", "iglob"}, schemaGlob, false},
+	{opNotGlobIgnoreCase, []string{"not glob ignore case", "notIGlob"}, schemaGlob, false},
+	{opBetween, []string{"in range"}, schemaRange, false},
+	{opNotBetween, []string{"not between", "not in range"}, schemaRange, false},
+	{opAnyElement, []string{"any element", "any"}, schemaFilter, false},
+	{opAllElements, []string{"all elements", "all"}, schemaFilter, false},
+	{opNoElement, []string{"no element", "none"}, schemaFilter, false},
+	{opCountElements, []string{"count elements", "count"}, schemaElementCount, false},
+	{opContainsAll, []string{"contains all", "hasAll"}, schemaList, false},
+	{opContainsAny, []string{"contains any", "hasAny", "intersects"}, schemaList, false},
+	{opContainsNone, []string{"contains none", "hasNone"}, schemaList, false},
+	{opSubsetOf, []string{"subset of"}, schemaList, false},
+	{opEqualSet, []string{"equal set", "sameSet"}, schemaList, false},
+	{opLengthEq, []string{"length ==", "length equals"}, schemaNumber, false},
+	{opLengthNe, []string{"length !=", "length not equal to"}, schemaNumber, false},
+	{opLengthLt, []string{"length <", "length less than"}, schemaNumber, false},
+	{opLengthLte, []string{"length <=", "length less than or equal to"}, schemaNumber, false},
+	{opLengthGt, []string{"length >", "length greater than"}, schemaNumber, false},
+	{opLengthGte, []string{"length >=", "length greater than or equal to"}, schemaNumber, false},
+	{opIsEmpty, []string{"is empty", "empty"}, nil, true},
+	{opIsNotEmpty, []string{"is not empty", "notEmpty"}, nil, true},
+	{opIsType, []string{"is type", "type"}, schemaType, false},
+	{opIsTimestamp, []string{"is timestamp"}, nil, true},
+	{opIsNumeric, []string{"is numeric"}, nil, true},
+	{opBefore, []string{"is before"}, schemaTimestamp, false},
+	{opAfter, []string{"is after"}, schemaTimestamp, false},
+	{opWithinLast, []string{"within last", "within the last"}, schemaDuration, false},
+	{opWithinNext, []string{"within next", "within the next"}, schemaDuration, false},
+}
+
+var operatorAliases = func() map[string]string {
+	m := map[string]string{}
+	for _, op := range operators {
+		m[op.Name] = op.Name
+		for _, alias := range op.Aliases {
+			m[alias] = op.Name
+		}
+	}
+	return m
+}()
+
+// canonicalOperator returns the canonical name of op and whether op is a known operator
+func canonicalOperator(op string) (string, bool) {
+	name, ok := operatorAliases[op]
+	return name, ok
+}
+
+// unaryOperator reports whether the canonical operator op ignores the value
+func unaryOperator(op string) bool {
+	for _, o := range operators {
+		if o.Name == op {
+			return o.Unary
+		}
+	}
+	return false
+}
+
+// isExistenceOperator reports whether the canonical operator op tests
+// whether the path resolves, rather than comparing the value it resolves to
+func isExistenceOperator(op string) bool {
+	return op == opExists || op == opNotExists || op == opIsNull || op == opIsNotNull
+}
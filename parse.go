@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseJSON decodes a filter document. Numbers are decoded as json.Number so
+// that numeric values compare the same way regardless of the source format.
+// The filters nested in the values of element operators and the paths values
+// refer to are decoded too.
+func ParseJSON(data []byte) (*Filter, error) {
+	var f Filter
+	err := json.NewDecoder(bytes.NewBuffer(data)).Decode(&f)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding like ParseJSON so that
+// filters decoded with encoding/json evaluate the same way
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	type plain Filter
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode((*plain)(f))
+	if err != nil {
+		return err
+	}
+	err = decodeElementFilter(f)
+	if err != nil {
+		return err
+	}
+	return decodePathRef(f)
+}
+
+// ParseYAML decodes a YAML filter document into the same tree ParseJSON
+// produces for the equivalent JSON document
+func ParseYAML(data []byte) (*Filter, error) {
+	var f Filter
+	err := yaml.Unmarshal(data, &f)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// ParseTOML decodes a TOML filter document into the same tree ParseJSON
+// produces for the equivalent JSON document. TOML has no null, so filters
+// comparing against null can not be expressed in TOML.
+func ParseTOML(data []byte) (*Filter, error) {
+	var f Filter
+	err := toml.Unmarshal(data, &f)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// ParseFile reads a filter document, choosing the format by extension: .yaml
+// and .yml are YAML, .toml is TOML and anything else JSON
+func ParseFile(name string) (*Filter, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var f *Filter
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		f, err = ParseYAML(data)
+	case ".toml":
+		f, err = ParseTOML(data)
+	default:
+		f, err = ParseJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (f *Filter) UnmarshalYAML(node *yaml.Node) error {
+	var doc interface{}
+	err := node.Decode(&doc)
+	if err != nil {
+		return err
+	}
+	return f.unmarshalDocument(doc)
+}
+
+// UnmarshalTOML implements toml.Unmarshaler
+func (f *Filter) UnmarshalTOML(doc interface{}) error {
+	return f.unmarshalDocument(doc)
+}
+
+// unmarshalDocument decodes a generic YAML or TOML document by way of JSON so
+// that struct tags, Template and JsonPath decoding and json.Number handling are
+// shared with the JSON format
+func (f *Filter) unmarshalDocument(doc interface{}) error {
+	normalized, err := normalizeDecoded(doc)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseJSON(data)
+	if err != nil {
+		return err
+	}
+	*f = *parsed
+	return nil
+}
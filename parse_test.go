@@ -0,0 +1,182 @@
+package filter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	filter, err := ParseYAML([]byte(`
+path: $.value
+operator: in
+value: [1, 2, 3]
+or:
+  template: "{{ .key }}"
+  value: test
+`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	fromJSON, err := ParseJSON([]byte(`{"path":"$.value","operator":"in","value":[1,2,3],"or":{"template":"{{ .key }}","value":"test"}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	a, _ := json.Marshal(filter)
+	b, _ := json.Marshal(fromJSON)
+	if string(a) != string(b) {
+		t.Log(string(a))
+		t.Log(string(b))
+		t.Error("yaml and json filters should be identical")
+		return
+	}
+	var msg1 interface{}
+	msg1, err = decodeJSONMessage([]byte(`{"value":1}`))
+	if err != nil {
+		t.Error("Failed to parse message 1", err)
+		return
+	}
+	var pass bool
+	pass, err = filter.Test(msg1)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("int 1 should pass")
+		return
+	}
+	var msg2 interface{}
+	msg2, err = decodeJSONMessage([]byte(`{"value":"1"}`))
+	if err != nil {
+		t.Error("Failed to parse message 2", err)
+		return
+	}
+	pass, err = filter.Test(msg2)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if pass {
+		t.Error("string 1 should not pass")
+		return
+	}
+	var msg3 interface{}
+	msg3, err = decodeJSONMessage([]byte(`{"value":4,"key":"test"}`))
+	if err != nil {
+		t.Error("Failed to parse message 3", err)
+		return
+	}
+	pass, err = filter.Test(msg3)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("template or clause should pass")
+		return
+	}
+}
+
+func TestParseYAMLNull(t *testing.T) {
+	filter, err := ParseYAML([]byte("path: $.value\nvalue: null\n"))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var msg1 interface{}
+	msg1, err = decodeJSONMessage([]byte(`{"value":null}`))
+	if err != nil {
+		t.Error("Failed to parse message 1", err)
+		return
+	}
+	var pass bool
+	pass, err = filter.Test(msg1)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("null should pass")
+		return
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	filter, err := ParseTOML([]byte(`
+path = "$.value"
+operator = ">"
+value = 5.5
+
+[and]
+path = "$.country"
+operator = "in"
+value = ["US", "CA"]
+`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	fromJSON, err := ParseJSON([]byte(`{"path":"$.value","operator":">","value":5.5,"and":{"path":"$.country","operator":"in","value":["US","CA"]}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	a, _ := json.Marshal(filter)
+	b, _ := json.Marshal(fromJSON)
+	if string(a) != string(b) {
+		t.Log(string(a))
+		t.Log(string(b))
+		t.Error("toml and json filters should be identical")
+		return
+	}
+	var msg1 interface{}
+	msg1, err = decodeJSONMessage([]byte(`{"value":6,"country":"CA"}`))
+	if err != nil {
+		t.Error("Failed to parse message 1", err)
+		return
+	}
+	var pass bool
+	pass, err = filter.Test(msg1)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("6 in CA should pass")
+		return
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	docs := map[string]string{
+		"rule.json": `{"path":"$.value","operator":">","value":5}`,
+		"rule.yaml": "path: $.value\noperator: '>'\nvalue: 5\n",
+		"rule.yml":  "path: $.value\noperator: '>'\nvalue: 5\n",
+		"rule.toml": "path = \"$.value\"\noperator = \">\"\nvalue = 5\n",
+	}
+	for name, doc := range docs {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(doc), 0644)
+		if err != nil {
+			t.Error("Failed to write filter", err)
+			return
+		}
+		filter, err := ParseFile(path)
+		if err != nil {
+			t.Errorf("%s: Failed to parse filter %s", name, err)
+			continue
+		}
+		if filter.Value != json.Number("5") || filter.Operator != ">" {
+			t.Errorf("%s: Unexpected filter %+v", name, filter)
+		}
+	}
+	_, err := ParseFile(filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Error("Missing file should fail")
+	}
+}
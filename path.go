@@ -0,0 +1,72 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a simple JSONPath: an object key, an array
+// index or a wildcard over all children.
+type pathSegment struct {
+	Key      string
+	Index    int
+	IsIndex  bool
+	Wildcard bool
+}
+
+// parsePath splits a simple JSONPath such as $.a.b[0]['c'] into segments. It
+// reports false for expressions it does not understand (filters, slices,
+// recursive descent, unions) so callers can fall back to the jsonpath package.
+func parsePath(path string) ([]pathSegment, bool) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, false
+	}
+	var segments []pathSegment
+	i := 1
+	for i < len(path) {
+		switch path[i] {
+		case '.':
+			i++
+			if i >= len(path) || path[i] == '.' {
+				return nil, false
+			}
+			end := i
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			key := path[i:end]
+			if key == "*" {
+				segments = append(segments, pathSegment{Wildcard: true})
+			} else {
+				segments = append(segments, pathSegment{Key: key})
+			}
+			i = end
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, false
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{Wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				key := inner[1 : len(inner)-1]
+				if strings.ContainsAny(key, `'"\`) {
+					return nil, false
+				}
+				segments = append(segments, pathSegment{Key: key})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return nil, false
+				}
+				segments = append(segments, pathSegment{Index: n, IsIndex: true})
+			}
+		default:
+			return nil, false
+		}
+	}
+	return segments, true
+}
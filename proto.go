@@ -0,0 +1,267 @@
+package filter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/the-control-group/go-jsonpath"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// protoResolver resolves paths in protocol buffer messages using
+// protoreflect. Path keys match field names or json_names. Enums resolve to
+// their names, Timestamps to RFC 3339 strings, Durations to seconds and
+// wrappers to the wrapped value. 64 bit integers are numbers rather than the
+// strings of the canonical JSON mapping so they compare numerically.
+//
+// Fields with presence which are not set are missing, fields without presence
+// resolve to their default value. Templates and scripts see the canonical JSON
+// mapping of the message.
+type protoResolver struct {
+	msg proto.Message
+}
+
+// protoNode is a value reached while walking a message. fd is nil for the
+// root message; elem is set for elements of the list fd.
+type protoNode struct {
+	v    protoreflect.Value
+	fd   protoreflect.FieldDescriptor
+	elem bool
+}
+
+// Resolve implements Resolver
+func (r protoResolver) Resolve(path jsonpath.JsonPath) (interface{}, error) {
+	segments, ok := cachedPath(path.String())
+	if !ok {
+		v, err := protoMessageValue(r.msg.ProtoReflect())
+		if err != nil {
+			return nil, err
+		}
+		return genericPathValue(v, path)
+	}
+	n := protoNode{v: protoreflect.ValueOfMessage(r.msg.ProtoReflect())}
+	for i, s := range segments {
+		next, ok, err := protoSegment(n, s)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// well-known types have no fields of their own, walk their JSON
+			v, err := protoNodeValue(n)
+			if err != nil {
+				return nil, err
+			}
+			rv := reflect.ValueOf(v)
+			for _, s := range segments[i:] {
+				rv, err = reflectSegment(rv, s)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return reflectValue(rv)
+		}
+		n = next
+	}
+	return protoNodeValue(n)
+}
+
+// Value implements Resolver, returning the canonical JSON mapping
+func (r protoResolver) Value() (interface{}, error) {
+	b, err := protojson.Marshal(r.msg)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONValue(b)
+}
+
+// protoSegment steps into n following s. It reports false when n is a
+// well-known type which must be walked through its JSON form.
+func protoSegment(n protoNode, s pathSegment) (protoNode, bool, error) {
+	switch {
+	case n.fd != nil && n.fd.IsList() && !n.elem:
+		if !s.IsIndex {
+			return n, false, notFound("unsupported value type list for select, expected map")
+		}
+		list := n.v.List()
+		if s.Index >= list.Len() {
+			return n, false, notFound("index %d out of bounds", s.Index)
+		}
+		return protoNode{list.Get(s.Index), n.fd, true}, true, nil
+	case n.fd != nil && n.fd.IsMap() && !n.elem:
+		if s.IsIndex {
+			return n, false, notFound("unsupported value type map for select, expected array")
+		}
+		key, err := protoMapKey(n.fd.MapKey(), s.Key)
+		if err != nil {
+			return n, false, notFound("unknown key %s", s.Key)
+		}
+		v := n.v.Map().Get(key)
+		if !v.IsValid() {
+			return n, false, notFound("unknown key %s", s.Key)
+		}
+		return protoNode{v, n.fd.MapValue(), false}, true, nil
+	case n.fd == nil || n.fd.Kind() == protoreflect.MessageKind || n.fd.Kind() == protoreflect.GroupKind:
+		m := n.v.Message()
+		if protoWellKnown(m.Descriptor().FullName()) {
+			return n, false, nil
+		}
+		if s.IsIndex {
+			return n, false, notFound("unsupported value type %s for select, expected array", m.Descriptor().FullName())
+		}
+		fields := m.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(s.Key))
+		if fd == nil {
+			fd = fields.ByJSONName(s.Key)
+		}
+		if fd == nil || (fd.HasPresence() && !m.Has(fd)) {
+			return n, false, notFound("unknown key %s", s.Key)
+		}
+		return protoNode{m.Get(fd), fd, false}, true, nil
+	default:
+		return n, false, notFound("unsupported value type %s for select", n.fd.Kind())
+	}
+}
+
+func protoMapKey(fd protoreflect.FieldDescriptor, key string) (protoreflect.MapKey, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(key).MapKey(), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(key)
+		return protoreflect.ValueOfBool(b).MapKey(), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(key, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)).MapKey(), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(key, 10, 64)
+		return protoreflect.ValueOfInt64(i).MapKey(), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(key, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(i)).MapKey(), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(key, 10, 64)
+		return protoreflect.ValueOfUint64(i).MapKey(), err
+	default:
+		return protoreflect.MapKey{}, fmt.Errorf("unsupported map key kind %s", fd.Kind())
+	}
+}
+
+func protoNodeValue(n protoNode) (interface{}, error) {
+	switch {
+	case n.fd == nil:
+		return protoMessageValue(n.v.Message())
+	case n.fd.IsList() && !n.elem:
+		list := n.v.List()
+		s := make([]interface{}, list.Len())
+		for i := range s {
+			v, err := protoScalarValue(n.fd, list.Get(i))
+			if err != nil {
+				return nil, err
+			}
+			s[i] = v
+		}
+		return s, nil
+	case n.fd.IsMap() && !n.elem:
+		m := map[string]interface{}{}
+		var err error
+		n.v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			m[k.String()], err = protoScalarValue(n.fd.MapValue(), v)
+			return err == nil
+		})
+		return m, err
+	default:
+		return protoScalarValue(n.fd, n.v)
+	}
+}
+
+func protoScalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (interface{}, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool(), nil
+	case protoreflect.StringKind:
+		return v.String(), nil
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name()), nil
+		}
+		return json.Number(strconv.FormatInt(int64(v.Enum()), 10)), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return json.Number(strconv.FormatInt(v.Int(), 10)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return json.Number(strconv.FormatUint(v.Uint(), 10)), nil
+	case protoreflect.FloatKind:
+		return json.Number(strconv.FormatFloat(v.Float(), 'g', -1, 32)), nil
+	case protoreflect.DoubleKind:
+		return json.Number(strconv.FormatFloat(v.Float(), 'g', -1, 64)), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoMessageValue(v.Message())
+	default:
+		return nil, fmt.Errorf("unsupported field kind %s", fd.Kind())
+	}
+}
+
+func protoWellKnown(name protoreflect.FullName) bool {
+	switch name {
+	case "google.protobuf.Timestamp", "google.protobuf.Duration",
+		"google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue",
+		"google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue",
+		"google.protobuf.Any", "google.protobuf.FieldMask", "google.protobuf.Empty":
+		return true
+	}
+	return false
+}
+
+// protoMessageValue converts m to the generic shapes Test compares
+func protoMessageValue(m protoreflect.Message) (interface{}, error) {
+	fields := m.Descriptor().Fields()
+	switch m.Descriptor().FullName() {
+	case "google.protobuf.Timestamp":
+		seconds := m.Get(fields.ByName("seconds")).Int()
+		nanos := m.Get(fields.ByName("nanos")).Int()
+		return time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano), nil
+	case "google.protobuf.Duration":
+		seconds := m.Get(fields.ByName("seconds")).Int()
+		nanos := m.Get(fields.ByName("nanos")).Int()
+		d := float64(seconds) + float64(nanos)/float64(time.Second)
+		return json.Number(strconv.FormatFloat(d, 'f', -1, 64)), nil
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		fd := fields.ByName("value")
+		return protoScalarValue(fd, m.Get(fd))
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue",
+		"google.protobuf.Any", "google.protobuf.FieldMask", "google.protobuf.Empty":
+		b, err := protojson.Marshal(m.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return decodeJSONValue(b)
+	}
+	out := map[string]interface{}{}
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !m.Has(fd) {
+			continue
+		}
+		v, err := protoNodeValue(protoNode{m.Get(fd), fd, false})
+		if err != nil {
+			return nil, err
+		}
+		out[fd.JSONName()] = v
+	}
+	return out, nil
+}
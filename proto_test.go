@@ -0,0 +1,167 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testOrderDescriptor describes
+//
+//	enum Status { UNKNOWN = 0; ACTIVE = 1; CLOSED = 2; }
+//	message Line { string sku = 1; }
+//	message Order {
+//	  int64 id = 1;
+//	  Status status = 2;
+//	  google.protobuf.Timestamp created = 3;
+//	  google.protobuf.Duration ttl = 4;
+//	  google.protobuf.StringValue note = 5;
+//	  repeated string tags = 6;
+//	  map<string, string> attrs = 7;
+//	  string display_name = 8;
+//	  repeated Line lines = 9;
+//	}
+func testOrderDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		}
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(), Label: label.Enum()}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("filter_test.proto"),
+		Package:    proto.String("filtertest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto", "google/protobuf/duration.proto", "google/protobuf/wrappers.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("ACTIVE"), Number: proto.Int32(1)},
+				{Name: proto.String("CLOSED"), Number: proto.Int32(2)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Line"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("sku", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false)},
+			},
+			{
+				Name: proto.String("Order"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, "", false),
+					field("status", 2, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".filtertest.Status", false),
+					field("created", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp", false),
+					field("ttl", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Duration", false),
+					field("note", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.StringValue", false),
+					field("tags", 6, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", true),
+					field("attrs", 7, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".filtertest.Order.AttrsEntry", true),
+					field("display_name", 8, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("lines", 9, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".filtertest.Line", true),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("AttrsEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+						field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				}},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal("Failed to build descriptor", err)
+	}
+	return fd.Messages().ByName("Order")
+}
+
+func TestFilterProto(t *testing.T) {
+	md := testOrderDescriptor(t)
+	fields := md.Fields()
+	order := dynamicpb.NewMessage(md)
+	order.Set(fields.ByName("id"), protoreflect.ValueOfInt64(9007199254740993))
+	order.Set(fields.ByName("status"), protoreflect.ValueOfEnum(1))
+	order.Set(fields.ByName("created"), protoreflect.ValueOfMessage(timestamppb.New(time.Now().Add(-6*time.Minute)).ProtoReflect()))
+	order.Set(fields.ByName("ttl"), protoreflect.ValueOfMessage(durationpb.New(90*time.Second).ProtoReflect()))
+	order.Set(fields.ByName("note"), protoreflect.ValueOfMessage(wrapperspb.String("hello").ProtoReflect()))
+	tags := order.Mutable(fields.ByName("tags")).List()
+	tags.Append(protoreflect.ValueOfString("a"))
+	tags.Append(protoreflect.ValueOfString("b"))
+	attrs := order.Mutable(fields.ByName("attrs")).Map()
+	attrs.Set(protoreflect.ValueOfString("tier").MapKey(), protoreflect.ValueOfString("gold"))
+	order.Set(fields.ByName("display_name"), protoreflect.ValueOfString("Ada"))
+	lines := order.Mutable(fields.ByName("lines")).List()
+	line := lines.NewElement()
+	line.Message().Set(line.Message().Descriptor().Fields().ByName("sku"), protoreflect.ValueOfString("X1"))
+	lines.Append(line)
+
+	cases := []struct {
+		filter string
+		pass   bool
+	}{
+		{`{"path":"$.id","operator":">","value":9007199254740000}`, true},
+		{`{"path":"$.status","operator":"in","value":["ACTIVE","CLOSED"]}`, true},
+		{`{"path":"$.status","value":"UNKNOWN"}`, false},
+		{`{"path":"$.created","operator":"olderThan","value":"5m"}`, true},
+		{`{"path":"$.ttl","operator":">=","value":90}`, true},
+		{`{"path":"$.note","value":"hello"}`, true},
+		{`{"path":"$.tags[1]","value":"b"}`, true},
+		{`{"path":"$.attrs.tier","value":"gold"}`, true},
+		{`{"path":"$.displayName","value":"Ada"}`, true},
+		{`{"path":"$.display_name","value":"Ada"}`, true},
+		{`{"path":"$.lines[0].sku","value":"X1"}`, true},
+		{`{"path":"$.missing","value":null}`, true},
+		{`{"template":"{{.status}}","value":"ACTIVE"}`, true},
+		{`{"script":{"interpreter":"js","script":"input.displayName === \"Ada\" && input.id === \"9007199254740993\""}}`, true},
+	}
+	for _, c := range cases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		pass, err := filter.Test(order)
+		if err != nil {
+			t.Errorf("%s: Filter test failed %s", c.filter, err)
+			continue
+		}
+		if pass != c.pass {
+			t.Errorf("%s should pass: %t", c.filter, c.pass)
+		}
+	}
+}
+
+func TestFilterProtoUnsetWrapper(t *testing.T) {
+	order := dynamicpb.NewMessage(testOrderDescriptor(t))
+	filter, err := ParseJSON([]byte(`{"path":"$.note","value":null,"and":{"path":"$.status","value":"UNKNOWN"}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var pass bool
+	pass, err = filter.Test(order)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("unset wrapper should be missing and unset enum should be its default")
+		return
+	}
+}
@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nickcarenza/go-template"
+	"github.com/the-control-group/go-timeutils"
+)
+
+// valueRange is the value of between and notBetween. A nil bound is open.
+type valueRange struct {
+	Min, Max                   interface{}
+	MinInclusive, MaxInclusive bool
+}
+
+// parseRange reads a [min, max] list or a {"min", "max", "minInclusive",
+// "maxInclusive"} object. Bounds are inclusive unless stated otherwise.
+func parseRange(v interface{}) (valueRange, error) {
+	r := valueRange{MinInclusive: true, MaxInclusive: true}
+	switch v := v.(type) {
+	case []interface{}:
+		if len(v) != 2 {
+			return r, fmt.Errorf("range expects [min, max], got %d items", len(v))
+		}
+		r.Min, r.Max = v[0], v[1]
+	case map[string]interface{}:
+		for k, b := range v {
+			var ok bool
+			switch k {
+			case "min":
+				r.Min, ok = b, true
+			case "max":
+				r.Max, ok = b, true
+			case "minInclusive":
+				r.MinInclusive, ok = b.(bool)
+			case "maxInclusive":
+				r.MaxInclusive, ok = b.(bool)
+			}
+			if !ok {
+				return r, fmt.Errorf("invalid range field %s", k)
+			}
+		}
+	default:
+		return r, fmt.Errorf("range expects [min, max] or an object with min and max")
+	}
+	if r.Min == nil && r.Max == nil {
+		return r, fmt.Errorf("range has neither min nor max")
+	}
+	return r, nil
+}
+
+// interpolate returns r with templated string bounds executed against msg and
+// bounds referring to other paths resolved. A referenced bound which does not
+// resolve, or resolves to null, fails the comparison with errMissingPath
+// rather than leaving the bound open, unless f.OnMissing makes it an error.
+func (r valueRange) interpolate(f *Filter, msg interface{}) (valueRange, error) {
+	for _, b := range []*interface{}{&r.Min, &r.Max} {
+		if ref, ok := (*b).(*PathRef); ok {
+			var err error
+			*b, err = ref.resolve(f, msg)
+			if err != nil {
+				return r, err
+			}
+			if *b == nil {
+				return r, errMissingPath
+			}
+			continue
+		}
+		s, ok := (*b).(string)
+		if !ok || !isTemplate(s) {
+			continue
+		}
+		data, err := messageValue(msg)
+		if err != nil {
+			return r, err
+		}
+		*b, err = template.Interpolate(data, s)
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// contains reports whether val lies within the range. Numbers compare
+// numerically, strings which all parse as numbers or as timestamps compare as
+// such and other strings compare lexicographically.
+func (r valueRange) contains(val interface{}) (bool, error) {
+	bounds := make([]interface{}, 0, 2)
+	for _, b := range []interface{}{r.Min, r.Max} {
+		if b != nil {
+			bounds = append(bounds, b)
+		}
+	}
+	var cmp func(b interface{}) (int, error)
+	switch v := val.(type) {
+	case float64, int:
+		n, _ := interfaceToFloat64(v)
+		cmp = func(b interface{}) (int, error) {
+			bn, err := interfaceToFloat64(b)
+			if err != nil {
+				return 0, err
+			}
+			return compareFloat(n, bn), nil
+		}
+	case string:
+		cmp = stringComparison(v, bounds)
+	default:
+		return false, fmt.Errorf("TypeAssertionError")
+	}
+	if r.Min != nil {
+		c, err := cmp(r.Min)
+		if err != nil {
+			return false, err
+		}
+		if c < 0 || c == 0 && !r.MinInclusive {
+			return false, nil
+		}
+	}
+	if r.Max != nil {
+		c, err := cmp(r.Max)
+		if err != nil {
+			return false, err
+		}
+		if c > 0 || c == 0 && !r.MaxInclusive {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// stringComparison picks how the string s compares with every bound: as a
+// number, as a timestamp or lexicographically
+func stringComparison(s string, bounds []interface{}) func(b interface{}) (int, error) {
+	if n, err := interfaceToFloat64(s); err == nil && allBounds(bounds, func(b interface{}) bool {
+		_, err := interfaceToFloat64(b)
+		return err == nil
+	}) {
+		return func(b interface{}) (int, error) {
+			bn, _ := interfaceToFloat64(b)
+			return compareFloat(n, bn), nil
+		}
+	}
+	if t, err := timeutils.ParseAny(s); err == nil && allBounds(bounds, func(b interface{}) bool {
+		bs, ok := b.(string)
+		if !ok {
+			return false
+		}
+		_, err := timeutils.ParseAny(bs)
+		return err == nil
+	}) {
+		return func(b interface{}) (int, error) {
+			bt, _ := timeutils.ParseAny(b.(string))
+			return compareTime(t, bt), nil
+		}
+	}
+	return func(b interface{}) (int, error) {
+		bs, ok := b.(string)
+		if !ok {
+			return 0, fmt.Errorf("TypeAssertionError")
+		}
+		return strings.Compare(s, bs), nil
+	}
+}
+
+func allBounds(bounds []interface{}, ok func(interface{}) bool) bool {
+	for _, b := range bounds {
+		if !ok(b) {
+			return false
+		}
+	}
+	return true
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	msg := map[string]interface{}{
+		"price":   19.99,
+		"qty":     "12",
+		"created": "2024-03-15T10:00:00Z",
+		"sku":     "M-200",
+		"limit":   20.0,
+		"flag":    true,
+	}
+	testFilterCases(t, msg, []filterCase{
+		{`{"path":"$.price","operator":"between","value":[10,20]}`, true},
+		{`{"path":"$.price","operator":"between","value":[20,30]}`, false},
+		{`{"path":"$.price","operator":"notBetween","value":[20,30]}`, true},
+		{`{"path":"$.price","operator":"between","value":{"min":10,"max":19.99,"maxInclusive":false}}`, false},
+		{`{"path":"$.price","operator":"between","value":{"min":19.99,"max":30}}`, true},
+		{`{"path":"$.price","operator":"between","value":{"min":19.99,"minInclusive":false}}`, false},
+		{`{"path":"$.price","operator":"between","value":{"max":"{{.limit}}"}}`, true},
+		{`{"path":"$.price","operator":"in range","value":["5","20"]}`, true},
+		{`{"path":"$.qty","operator":"between","value":[9,100]}`, true},
+		{`{"path":"$.created","operator":"between","value":["2024-03-01T00:00:00Z","2024-04-01T00:00:00Z"]}`, true},
+		{`{"path":"$.created","operator":"between","value":["2024-03-15T10:00:01Z","2024-04-01T00:00:00Z"]}`, false},
+		{`{"path":"$.sku","operator":"between","value":["M-100","M-300"]}`, true},
+		{`{"path":"$.sku","operator":"not between","value":["M-100","M-150"]}`, true},
+		{`{"path":"$.missing","operator":"between","value":[1,2]}`, false},
+	})
+	testFilterErrors(t, msg, []string{
+		`{"path":"$.price","operator":"between","value":[1]}`,
+		`{"path":"$.price","operator":"between","value":{"low":1}}`,
+		`{"path":"$.price","operator":"between","value":{}}`,
+		`{"path":"$.price","operator":"between","value":["a","b"]}`,
+		`{"path":"$.flag","operator":"between","value":[1,2]}`,
+	})
+}
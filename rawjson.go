@@ -0,0 +1,264 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/the-control-group/go-jsonpath"
+)
+
+// TestJSON evaluates the filter against an encoded JSON message. Simple paths
+// are resolved by scanning raw and decoding only the referenced values; the
+// whole message is decoded only for templates, scripts and paths using
+// filters, slices or wildcards. Parts of raw which are skipped are only
+// validated when a path is not found, so that malformed JSON is an error
+// rather than a missing value.
+func (f *Filter) TestJSON(raw []byte) (bool, error) {
+	return f.Test(&RawJSON{Data: raw})
+}
+
+// RawJSON is an encoded JSON message which resolves paths lazily. It caches
+// what it decodes and is not safe for concurrent use.
+type RawJSON struct {
+	Data     []byte
+	resolved map[string]interface{}
+	decoded  interface{}
+	err      error
+	done     bool
+}
+
+// Resolve implements Resolver
+func (r *RawJSON) Resolve(path jsonpath.JsonPath) (interface{}, error) {
+	key := path.String()
+	if v, ok := r.resolved[key]; ok {
+		return v, nil
+	}
+	segments, ok := cachedPath(key)
+	if !ok {
+		v, err := r.Value()
+		if err != nil {
+			return nil, err
+		}
+		return genericPathValue(v, path)
+	}
+	raw, err := scanJSON(r.Data, segments)
+	if errors.Is(err, ErrNotFound) && !json.Valid(r.Data) {
+		_, err = r.Value()
+	}
+	if err != nil {
+		return nil, err
+	}
+	v, err := decodeJSONValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	if r.resolved == nil {
+		r.resolved = map[string]interface{}{}
+	}
+	r.resolved[key] = v
+	return v, nil
+}
+
+// Value implements Resolver, decoding the whole message once
+func (r *RawJSON) Value() (interface{}, error) {
+	if !r.done {
+		r.decoded, r.err = decodeJSONValue(r.Data)
+		r.done = true
+	}
+	return r.decoded, r.err
+}
+
+// MarshalJSON implements json.Marshaler
+func (r *RawJSON) MarshalJSON() ([]byte, error) {
+	return json.RawMessage(r.Data).MarshalJSON()
+}
+
+// scanJSON returns the encoded value at segments without decoding anything
+// else. Like encoding/json, the last of duplicate object keys wins.
+func scanJSON(data []byte, segments []pathSegment) ([]byte, error) {
+	start := skipSpace(data, 0)
+	for _, s := range segments {
+		if start >= len(data) {
+			return nil, fmt.Errorf("unexpected end of JSON input")
+		}
+		var err error
+		if s.IsIndex {
+			start, err = scanIndex(data, start, s.Index)
+		} else {
+			start, err = scanKey(data, start, s.Key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	end, err := skipValue(data, start)
+	if err != nil {
+		return nil, err
+	}
+	return data[start:end], nil
+}
+
+// scanKey returns the offset of the value of key in the object at i
+func scanKey(data []byte, i int, key string) (int, error) {
+	if data[i] != '{' {
+		return 0, notFound("unsupported value type for select, expected map")
+	}
+	found := -1
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return 0, notFound("unknown key %s", key)
+	}
+	for i < len(data) {
+		if data[i] != '"' {
+			return 0, fmt.Errorf("invalid character %q looking for beginning of object key string", data[i])
+		}
+		end, err := skipString(data, i)
+		if err != nil {
+			return 0, err
+		}
+		name, err := unquote(data[i:end])
+		if err != nil {
+			return 0, err
+		}
+		i = skipSpace(data, end)
+		if i >= len(data) || data[i] != ':' {
+			return 0, fmt.Errorf("invalid character after object key")
+		}
+		i = skipSpace(data, i+1)
+		if name == key {
+			found = i
+		}
+		i, err = skipValue(data, i)
+		if err != nil {
+			return 0, err
+		}
+		i = skipSpace(data, i)
+		if i < len(data) && data[i] == ',' {
+			i = skipSpace(data, i+1)
+			continue
+		}
+		if i < len(data) && data[i] == '}' {
+			if found < 0 {
+				return 0, notFound("unknown key %s", key)
+			}
+			return found, nil
+		}
+		return 0, fmt.Errorf("invalid character after object key:value pair")
+	}
+	return 0, fmt.Errorf("unexpected end of JSON input")
+}
+
+// scanIndex returns the offset of element index of the array at i
+func scanIndex(data []byte, i int, index int) (int, error) {
+	if data[i] != '[' {
+		return 0, notFound("unsupported value type for select, expected array")
+	}
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == ']' {
+		return 0, notFound("index %d out of bounds", index)
+	}
+	for n := 0; i < len(data); n++ {
+		if n == index {
+			return i, nil
+		}
+		var err error
+		i, err = skipValue(data, i)
+		if err != nil {
+			return 0, err
+		}
+		i = skipSpace(data, i)
+		if i < len(data) && data[i] == ',' {
+			i = skipSpace(data, i+1)
+			continue
+		}
+		if i < len(data) && data[i] == ']' {
+			return 0, notFound("index %d out of bounds", index)
+		}
+		return 0, fmt.Errorf("invalid character after array element")
+	}
+	return 0, fmt.Errorf("unexpected end of JSON input")
+}
+
+// skipValue returns the offset just past the value starting at i
+func skipValue(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, fmt.Errorf("unexpected end of JSON input")
+	}
+	switch data[i] {
+	case '"':
+		return skipString(data, i)
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				end, err := skipString(data, i)
+				if err != nil {
+					return 0, err
+				}
+				i = end
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+			i++
+		}
+		return 0, fmt.Errorf("unexpected end of JSON input")
+	default:
+		start := i
+		for i < len(data) {
+			switch data[i] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				if i == start {
+					return 0, fmt.Errorf("invalid character %q looking for beginning of value", data[i])
+				}
+				return i, nil
+			}
+			i++
+		}
+		return i, nil
+	}
+}
+
+// skipString returns the offset just past the string starting at i
+func skipString(data []byte, i int) (int, error) {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unexpected end of JSON input")
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// unquote decodes a JSON string, avoiding encoding/json when there are no escapes
+func unquote(quoted []byte) (string, error) {
+	for _, c := range quoted {
+		if c == '\\' {
+			var s string
+			err := json.Unmarshal(quoted, &s)
+			return s, err
+		}
+	}
+	return string(quoted[1 : len(quoted)-1]), nil
+}
@@ -0,0 +1,100 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterTestJSON(t *testing.T) {
+	msg := []byte(`{"skip":{"a":[1,"}]",{"b":"\"{"}]},"value" : 5, "nested":{"list":[ "x", {"k":"v"} ]},"esc\"aped":true,"dup":1,"dup":2}`)
+	cases := []struct {
+		filter string
+		pass   bool
+	}{
+		{`{"path":"$.value","operator":">","value":4}`, true},
+		{`{"path":"$.value","operator":"in","value":[1,5]}`, true},
+		{`{"path":"$.nested.list[1].k","value":"v"}`, true},
+		{`{"path":"$.nested.list[0]","value":"x"}`, true},
+		{`{"path":"$.nested.list[2]","value":null}`, true},
+		{`{"path":"$.missing","value":null}`, true},
+		{`{"path":"$[\"esc\\\"aped\"]","value":true}`, true},
+		{`{"path":"$.dup","value":2}`, true},
+		{`{"path":"$.skip.a[1]","value":"}]"}`, true},
+		{`{"template":"{{.value}}","value":"5"}`, true},
+	}
+	for _, c := range cases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		pass, err := filter.TestJSON(msg)
+		if err != nil {
+			t.Errorf("%s: Filter test failed %s", c.filter, err)
+			continue
+		}
+		if pass != c.pass {
+			t.Errorf("%s should pass: %t", c.filter, c.pass)
+		}
+		decoded, err := decodeJSONMessage(msg)
+		if err != nil {
+			t.Error("Failed to parse message", err)
+			return
+		}
+		full, err := filter.Test(decoded)
+		if err != nil {
+			t.Errorf("%s: Filter test failed %s", c.filter, err)
+			continue
+		}
+		if full != pass {
+			t.Errorf("%s: TestJSON and Test disagree", c.filter)
+		}
+	}
+}
+
+func TestFilterTestJSONMalformed(t *testing.T) {
+	filters := []string{
+		`{"path":"$.value","operator":"notExists"}`,
+		`{"path":"$.value","value":null}`,
+		`{"path":"$.value","operator":"!=","value":"a"}`,
+		`{"path":"$.value","onMissing":"false","value":null}`,
+	}
+	msgs := []string{`this is not json`, `{"x": tru}`, `{"x": {"y": [1,]}}`, `{"x": 1`, `[1, 2`}
+	for _, c := range filters {
+		filter, err := ParseJSON([]byte(c))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		for _, msg := range msgs {
+			if _, err := filter.TestJSON([]byte(msg)); err == nil {
+				t.Errorf("%s against %s should fail", c, msg)
+			}
+		}
+	}
+}
+
+func BenchmarkFilterTestJSON(b *testing.B) {
+	filter, err := ParseJSON([]byte(`{"path":"$.value","operator":">","value":4}`))
+	if err != nil {
+		b.Error("Failed to parse filter", err)
+		return
+	}
+	msg := []byte(`{"padding":"` + strings.Repeat("x", 4096) + `","value":5}`)
+	for i := 0; i < b.N; i++ {
+		filter.TestJSON(msg)
+	}
+}
+
+func BenchmarkFilterTestDecoded(b *testing.B) {
+	filter, err := ParseJSON([]byte(`{"path":"$.value","operator":">","value":4}`))
+	if err != nil {
+		b.Error("Failed to parse filter", err)
+		return
+	}
+	msg := []byte(`{"padding":"` + strings.Repeat("x", 4096) + `","value":5}`)
+	for i := 0; i < b.N; i++ {
+		decoded, _ := decodeJSONMessage(msg)
+		filter.Test(decoded)
+	}
+}
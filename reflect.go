@@ -0,0 +1,400 @@
+package filter
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/the-control-group/go-jsonpath"
+)
+
+// reflectResolver resolves paths in Go values of any type, honoring json
+// struct tags, so structs can be filtered without a JSON round trip
+type reflectResolver struct {
+	msg interface{}
+}
+
+// Resolve implements Resolver
+func (r reflectResolver) Resolve(path jsonpath.JsonPath) (interface{}, error) {
+	segments, ok := cachedPath(path.String())
+	if !ok {
+		// not a simple path, walk the generic form with the jsonpath package
+		v, err := r.Value()
+		if err != nil {
+			return nil, err
+		}
+		return genericPathValue(v, path)
+	}
+	v := reflect.ValueOf(r.msg)
+	for _, s := range segments {
+		var err error
+		v, err = reflectSegment(v, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reflectValue(v)
+}
+
+// Value implements Resolver
+func (r reflectResolver) Value() (interface{}, error) {
+	return reflectValue(reflect.ValueOf(r.msg))
+}
+
+type cachedSegments struct {
+	segments []pathSegment
+	ok       bool
+}
+
+var pathCache sync.Map
+
+// cachedPath parses simple paths without wildcards, caching the result
+func cachedPath(path string) ([]pathSegment, bool) {
+	if cached, ok := pathCache.Load(path); ok {
+		c := cached.(cachedSegments)
+		return c.segments, c.ok
+	}
+	segments, ok := parsePath(path)
+	for _, s := range segments {
+		if s.Wildcard {
+			ok = false
+		}
+	}
+	pathCache.Store(path, cachedSegments{segments, ok})
+	return segments, ok
+}
+
+// reflectSegment steps into v following s
+func reflectSegment(v reflect.Value, s pathSegment) (reflect.Value, error) {
+	v = reflectIndirect(v)
+	if v.IsValid() && v.CanInterface() && reflectMarshaler(v) {
+		// walk the JSON the value marshals to
+		g, err := reflectValue(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v = reflectIndirect(reflect.ValueOf(g))
+	}
+	if !v.IsValid() {
+		return v, notFound("unknown key %s", s.Key)
+	}
+	if s.IsIndex {
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+				// []byte is a base64 string in JSON
+				break
+			}
+			if s.Index >= v.Len() {
+				return reflect.Value{}, notFound("index %d out of bounds", s.Index)
+			}
+			return v.Index(s.Index), nil
+		}
+		return reflect.Value{}, notFound("unsupported value type %s for select, expected array", v.Type())
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, field := range cachedFields(v.Type()) {
+			if field.Name != s.Key {
+				continue
+			}
+			fv, ok := fieldByIndex(v, field.Index)
+			if !ok || (field.OmitEmpty && isEmptyValue(fv)) {
+				break
+			}
+			return fv, nil
+		}
+		return reflect.Value{}, notFound("unknown key %s", s.Key)
+	case reflect.Map:
+		if kt := v.Type().Key(); kt.Kind() == reflect.String {
+			mv := v.MapIndex(reflect.ValueOf(s.Key).Convert(kt))
+			if !mv.IsValid() {
+				return mv, notFound("unknown key %s", s.Key)
+			}
+			return mv, nil
+		}
+		for _, k := range v.MapKeys() {
+			name, err := mapKey(k)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if name == s.Key {
+				return v.MapIndex(k), nil
+			}
+		}
+		return reflect.Value{}, notFound("unknown key %s", s.Key)
+	}
+	return reflect.Value{}, notFound("unsupported value type %s for select, expected map", v.Type())
+}
+
+// reflectValue converts v to the shapes encoding/json produces with UseNumber
+func reflectValue(v reflect.Value) (interface{}, error) {
+	v = reflectIndirect(v)
+	if !v.IsValid() {
+		return nil, nil
+	}
+	// values reached through unexported embedded structs can not be interfaced
+	if v.CanInterface() {
+		if t, ok := v.Interface().(time.Time); ok {
+			return t.Format(time.RFC3339Nano), nil
+		}
+		if m, ok := v.Interface().(json.Marshaler); ok {
+			b, err := m.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			return decodeJSONValue(b)
+		}
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			return string(b), nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(v.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Number(strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return json.Number(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return nil, nil
+			}
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+			}
+		}
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			e, err := reflectValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			s[i] = e
+		}
+		return s, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := mapKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			e, err := reflectValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			m[k] = e
+		}
+		return m, nil
+	case reflect.Struct:
+		fields := cachedFields(v.Type())
+		m := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			fv, ok := fieldByIndex(v, field.Index)
+			if !ok || (field.OmitEmpty && isEmptyValue(fv)) {
+				continue
+			}
+			e, err := reflectValue(fv)
+			if err != nil {
+				return nil, err
+			}
+			m[field.Name] = e
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %s", v.Type())
+	}
+}
+
+// reflectIndirect follows pointers and interfaces, returning the zero Value for nil
+func reflectIndirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		if v.Kind() == reflect.Pointer && reflectMarshaler(v) && !reflectMarshaler(v.Elem()) {
+			// pointer receiver marshaler
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// reflectMarshaler reports whether v marshals itself to JSON
+func reflectMarshaler(v reflect.Value) bool {
+	t := v.Type()
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if k.CanInterface() {
+		if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			return string(b), err
+		}
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported map key type %s", k.Type())
+	}
+}
+
+type structField struct {
+	Name      string
+	Index     []int
+	OmitEmpty bool
+}
+
+var fieldCache sync.Map
+
+// cachedFields returns the JSON fields of struct type t following the
+// encoding/json rules for tags and embedded structs
+func cachedFields(t reflect.Type) []structField {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]structField)
+	}
+	fields := typeFields(t, nil)
+	// fields of shallower depth win, ambiguous names at the same depth are dropped
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].Name != fields[j].Name {
+			return fields[i].Name < fields[j].Name
+		}
+		return len(fields[i].Index) < len(fields[j].Index)
+	})
+	var out []structField
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].Name == fields[i].Name {
+			j++
+		}
+		if j-i == 1 || len(fields[i].Index) < len(fields[i+1].Index) {
+			out = append(out, fields[i])
+		}
+		i = j
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return lessIndex(out[i].Index, out[j].Index)
+	})
+	fieldCache.Store(t, out)
+	return out
+}
+
+func typeFields(t reflect.Type, index []int) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(append([]int{}, index...), i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, typeFields(ft, idx)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, structField{
+			Name:      name,
+			Index:     idx,
+			OmitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	return fields
+}
+
+func lessIndex(a, b []int) bool {
+	for i := range a {
+		if i >= len(b) {
+			return false
+		}
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex reporting false for nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// decodeJSONValue decodes data the way messages are decoded, with UseNumber
+func decodeJSONValue(data []byte) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	return v, err
+}
@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	Country string `json:"country"`
+}
+
+type testBase struct {
+	ID int64 `json:"id"`
+}
+
+type testOrder struct {
+	testBase
+	Amount   float32           `json:"amount"`
+	Created  time.Time         `json:"created"`
+	Address  *testAddress      `json:"address,omitempty"`
+	Tags     []string          `json:"tags"`
+	Counts   map[int]uint8     `json:"counts"`
+	Internal string            `json:"-"`
+	Note     string            `json:"note,omitempty"`
+	Extra    map[string]string `json:"extra"`
+}
+
+func TestFilterStruct(t *testing.T) {
+	order := testOrder{
+		testBase: testBase{ID: 7},
+		Amount:   101.5,
+		Created:  time.Now().Add(-6 * time.Minute),
+		Address:  &testAddress{Country: "CA"},
+		Tags:     []string{"a", "b"},
+		Counts:   map[int]uint8{3: 9},
+		Internal: "secret",
+		Extra:    map[string]string{"k": "v"},
+	}
+	cases := []struct {
+		filter string
+		pass   bool
+	}{
+		{`{"path":"$.id","value":7}`, true},
+		{`{"path":"$.amount","operator":">","value":100}`, true},
+		{`{"path":"$.created","operator":"olderThan","value":"5m"}`, true},
+		{`{"path":"$.address.country","operator":"in","value":["US","CA"]}`, true},
+		{`{"path":"$.tags[1]","value":"b"}`, true},
+		{`{"path":"$.counts[\"3\"]","value":9}`, true},
+		{`{"path":"$.Internal","value":"secret"}`, false},
+		{`{"path":"$.note","value":null}`, true},
+		{`{"path":"$.extra.k","value":"v"}`, true},
+		{`{"template":"{{.address.country}}","value":"CA"}`, true},
+		{`{"script":{"interpreter":"js","script":"input.tags.length === 2"}}`, true},
+	}
+	for _, c := range cases {
+		filter, err := ParseJSON([]byte(c.filter))
+		if err != nil {
+			t.Error("Failed to parse filter", err)
+			return
+		}
+		for _, msg := range []interface{}{order, &order} {
+			pass, err := filter.Test(msg)
+			if err != nil {
+				t.Errorf("%s: Filter test failed %s", c.filter, err)
+				continue
+			}
+			if pass != c.pass {
+				t.Errorf("%s should pass: %t", c.filter, c.pass)
+			}
+		}
+	}
+}
+
+func TestFilterStructUnsupported(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.events","operator":"notExists"}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	msg := struct {
+		Events chan int `json:"events"`
+	}{make(chan int)}
+	_, err = filter.Test(msg)
+	if err == nil {
+		t.Error("unsupported value type should fail, not be missing")
+	}
+}
+
+func TestFilterStructNilPointer(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.address.country","value":null}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var pass bool
+	pass, err = filter.Test(testOrder{})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("missing address should pass")
+		return
+	}
+}
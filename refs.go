@@ -0,0 +1,108 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/the-control-group/go-jsonpath"
+)
+
+// PathRef is a filter value referring to another value of the message,
+// written {"path": "$.budget"}. It resolves to the value at Path as it is, so
+// two numeric or timestamp fields compare like literals where an interpolated
+// template would yield a string.
+type PathRef struct {
+	Path jsonpath.JsonPath `json:"path"`
+}
+
+// Ref builds a value referring to the message value at path. It panics if
+// path does not parse.
+func Ref(path string) *PathRef {
+	return &PathRef{Path: jsonpath.MustParsePath(path)}
+}
+
+// decodePathRef replaces a {"path": ...} value of f, and the bounds of its
+// range, by PathRefs. Element operators are left alone, their values are
+// nested filters.
+func decodePathRef(f *Filter) error {
+	op, _ := canonicalOperator(f.Operator)
+	if isElementOperator(op) {
+		return nil
+	}
+	ref, err := pathRefValue(f.Value)
+	if err != nil {
+		return err
+	}
+	if ref != nil {
+		f.Value = ref
+	}
+	if op == opBetween || op == opNotBetween {
+		return decodeRangeRefs(f.Value)
+	}
+	return nil
+}
+
+// decodeRangeRefs replaces the {"path": ...} bounds of the range v by PathRefs
+func decodeRangeRefs(v interface{}) error {
+	decode := func(b interface{}) (interface{}, error) {
+		ref, err := pathRefValue(b)
+		if ref == nil {
+			return b, err
+		}
+		return ref, nil
+	}
+	var err error
+	switch v := v.(type) {
+	case []interface{}:
+		for i := 0; i < len(v) && err == nil; i++ {
+			v[i], err = decode(v[i])
+		}
+	case map[string]interface{}:
+		for _, k := range []string{"min", "max"} {
+			if b, ok := v[k]; ok && err == nil {
+				v[k], err = decode(b)
+			}
+		}
+	}
+	return err
+}
+
+// pathRefValue converts v to a *PathRef if it is an object holding nothing
+// but a path, returning nil for other values
+func pathRefValue(v interface{}) (*PathRef, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, nil
+	}
+	path, ok := m["path"].(string)
+	if !ok {
+		return nil, nil
+	}
+	ref := &PathRef{}
+	quoted, _ := json.Marshal(path)
+	err := ref.Path.UnmarshalJSON(quoted)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value path %s: %w", path, err)
+	}
+	return ref, nil
+}
+
+// resolve returns the message value ref refers to, with numbers converted to
+// float64. A path which does not resolve is treated as f.OnMissing says.
+func (ref *PathRef) resolve(f *Filter, msg interface{}) (interface{}, error) {
+	v, err := getPathValue(msg, ref.Path)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		switch f.OnMissing {
+		case MissingFalse:
+			return nil, errMissingPath
+		case MissingError:
+			return nil, fmt.Errorf("missing path %s: %w", ref.Path.String(), err)
+		}
+		return nil, nil
+	}
+	return numberValue(v)
+}
@@ -0,0 +1,90 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPathRef(t *testing.T) {
+	msg, err := decodeJSONMessage([]byte(`{"spent":120,"budget":100,"limit":"150","country":"CA","allowed":["US","CA"],"name":"a*","range":[100,200],"rounded":[1e2,200.0],"quota":{"max":5,"steps":[1,2]},"plan":{"max":5.0,"steps":[1.0,2e0]}}`))
+	if err != nil {
+		t.Error("Failed to parse message", err)
+		return
+	}
+	testFilterCases(t, msg, []filterCase{
+		{`{"path":"$.spent","operator":">","value":{"path":"$.budget"}}`, true},
+		{`{"path":"$.budget","operator":">","value":{"path":"$.spent"}}`, false},
+		{`{"path":"$.spent","operator":"<","value":{"path":"$.limit"}}`, true},
+		{`{"path":"$.spent","operator":"==","value":{"path":"$.spent"}}`, true},
+		{`{"path":"$.allowed","operator":"==","value":{"path":"$.allowed"}}`, true},
+		{`{"path":"$.allowed","operator":"!=","value":{"path":"$.range"}}`, true},
+		{`{"path":"$.spent","operator":"==","value":{"path":"$.allowed"}}`, false},
+		{`{"path":"$.range","operator":"==","value":{"path":"$.rounded"}}`, true},
+		{`{"path":"$.quota","operator":"==","value":{"path":"$.plan"}}`, true},
+		{`{"path":"$.quota","operator":"!=","value":{"path":"$.plan"}}`, false},
+		{`{"path":"$.quota.steps","operator":"==","value":{"path":"$.range"}}`, false},
+		{`{"path":"$.country","operator":"in","value":{"path":"$.allowed"}}`, true},
+		{`{"path":"$.country","operator":"notIn","value":{"path":"$.allowed"}}`, false},
+		{`{"path":"$.country","operator":"in","value":{"path":"$.missing"}}`, false},
+		{`{"path":"$.country","operator":"notIn","value":{"path":"$.missing"}}`, true},
+		{`{"path":"$.country","operator":"in","value":{"path":"$.missing"},"onMissing":"false"}`, false},
+		{`{"path":"$.allowed","operator":"containsAny","value":{"path":"$.allowed"}}`, true},
+		{`{"path":"$.spent","operator":"between","value":{"path":"$.range"}}`, true},
+		{`{"path":"$.name","operator":"glob","value":{"path":"$.name"}}`, true},
+		{`{"path":"$.spent","operator":"==","value":{"path":"$.missing"}}`, false},
+		{`{"path":"$.spent","operator":"!=","value":{"path":"$.missing"},"onMissing":"false"}`, false},
+		{`{"path":"$.spent","operator":"exists","value":{"path":"$.missing"},"onMissing":"false"}`, true},
+		{`{"path":"$.spent","operator":"is not null","value":{"path":"$.missing"},"onMissing":"false"}`, true},
+		{`{"path":"$.spent","value":{"path":"$.budget","other":1}}`, false},
+	})
+	testFilterErrors(t, msg, []string{
+		`{"path":"$.spent","operator":">","value":{"path":"$.missing"},"onMissing":"error"}`,
+	})
+}
+
+func TestPathRefElements(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.items","operator":"anyElement","value":{"path":"$.qty","operator":">","value":{"path":"$.min"}}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	// references in nested filters resolve against the element
+	pass, err := filter.Test(map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"qty": 1.0, "min": 2.0},
+		map[string]interface{}{"qty": 3.0, "min": 2.0},
+	}})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("second element should pass")
+	}
+}
+
+func TestPathRefInvalid(t *testing.T) {
+	_, err := ParseJSON([]byte(`{"path":"$.a","value":{"path":"$.b[?("}}`))
+	if err == nil {
+		t.Error("invalid value path should fail to parse")
+	}
+}
+
+func TestPathRefUnmarshal(t *testing.T) {
+	var filter Filter
+	err := json.Unmarshal([]byte(`{"path":"$.spent","operator":">","value":{"path":"$.budget"},"and":{"path":"$.tags","operator":"anyElement","value":{"path":"$","value":"vip"}}}`), &filter)
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	if _, ok := filter.Value.(*PathRef); !ok {
+		t.Errorf("expected value to decode as a reference, got %T", filter.Value)
+	}
+	pass, err := filter.Test(map[string]interface{}{"spent": 120, "budget": 100, "tags": []interface{}{"vip"}})
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if !pass {
+		t.Error("spent over budget should pass")
+	}
+}
@@ -0,0 +1,79 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/the-control-group/go-jsonpath"
+	"google.golang.org/protobuf/proto"
+)
+
+// Resolver is implemented by messages which resolve paths themselves instead
+// of being walked as decoded JSON. Values must have the shapes encoding/json
+// produces with UseNumber: map[string]interface{}, []interface{}, string,
+// bool, json.Number and nil.
+type Resolver interface {
+	// Resolve returns the value at path. The error matches ErrNotFound with
+	// errors.Is if the path does not exist, any other error fails Test.
+	Resolve(path jsonpath.JsonPath) (interface{}, error)
+	// Value returns the whole message, used by templates and scripts
+	Value() (interface{}, error)
+}
+
+// ErrNotFound is matched by resolve errors meaning the path does not exist in
+// the message, as opposed to a message which cannot be read
+var ErrNotFound = errors.New("path not found")
+
+// notFoundError is an ErrNotFound describing the key or index not found
+type notFoundError string
+
+func (e notFoundError) Error() string {
+	return string(e)
+}
+
+// Is implements errors.Is
+func (e notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func notFound(format string, args ...interface{}) error {
+	return notFoundError(fmt.Sprintf(format, args...))
+}
+
+// genericPathValue resolves path in a decoded value. Decoded values are
+// always readable, so every error means the path does not exist.
+func genericPathValue(v interface{}, path jsonpath.JsonPath) (interface{}, error) {
+	v, err := jsonpath.GetPathValue(v, path)
+	if err != nil {
+		return nil, notFound("%s", err)
+	}
+	return v, nil
+}
+
+// getPathValue resolves path in any message Test accepts
+func getPathValue(msg interface{}, path jsonpath.JsonPath) (interface{}, error) {
+	switch m := msg.(type) {
+	case Resolver:
+		return m.Resolve(path)
+	case proto.Message:
+		return protoResolver{m}.Resolve(path)
+	case map[string]interface{}, []interface{}, nil:
+		return genericPathValue(msg, path)
+	default:
+		return reflectResolver{msg}.Resolve(path)
+	}
+}
+
+// messageValue returns msg in the generic shape templates and scripts expect
+func messageValue(msg interface{}) (interface{}, error) {
+	switch m := msg.(type) {
+	case Resolver:
+		return m.Value()
+	case proto.Message:
+		return protoResolver{m}.Value()
+	case map[string]interface{}, []interface{}, nil:
+		return msg, nil
+	default:
+		return reflectResolver{msg}.Value()
+	}
+}
@@ -0,0 +1,258 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaID is the $id of the JSON Schema published as filter.schema.json
+const SchemaID = "https://raw.githubusercontent.com/nickcarenza/go-filter/main/filter.schema.json"
+
+// durationPattern matches the strings timeutils.ParseApproxBigDuration understands
+const durationPattern = `^(~ )?-?\s*(\d+ ?(ns|nanos|nanoseconds?|µs?|ms|s|secs?|m|mins?|h|hrs?|d|days?|mos?|y|yrs?)[\s,]*)+$`
+
+// Value shapes shared by operators in the registry
+var (
+	schemaList     = map[string]interface{}{"type": "array"}
+	schemaNumber   = map[string]interface{}{"type": []string{"number", "string"}}
+	schemaDuration = map[string]interface{}{"$ref": "#/definitions/duration"}
+	schemaRegexp   = map[string]interface{}{"type": "string", "format": "regex"}
+	schemaString   = map[string]interface{}{"type": "string"}
+	schemaGlob     = map[string]interface{}{"type": "string", "format": "glob"}
+	schemaRange    = map[string]interface{}{"$ref": "#/definitions/range"}
+	// timestamps are anything timeutils.ParseAny understands
+	schemaTimestamp = map[string]interface{}{"type": "string"}
+	// nested filters are evaluated against each element of the array
+	schemaFilter       = map[string]interface{}{"$ref": "#/definitions/filter"}
+	schemaElementCount = map[string]interface{}{"$ref": "#/definitions/elementCount"}
+	schemaType         = map[string]interface{}{"$ref": "#/definitions/type"}
+	// range bounds may refer to other paths
+	schemaBound = map[string]interface{}{"anyOf": []interface{}{
+		map[string]interface{}{"type": []string{"number", "string", "null"}},
+		map[string]interface{}{"$ref": "#/definitions/pathRef"},
+	}}
+)
+
+var filterFieldSchemas = map[string]interface{}{
+	"template": map[string]interface{}{
+		"type":        []string{"string", "null"},
+		"description": "Go template executed against the message. Its output is compared instead of the value at path.",
+	},
+	"path": map[string]interface{}{
+		"type":        "string",
+		"pattern":     `^(\$.*)?$`,
+		"description": "JSONPath of the message value to compare.",
+	},
+	"value": map[string]interface{}{
+		"description": "Value to compare against. Strings are interpolated as templates and {\"path\": ...} refers to another value of the message.",
+	},
+	"operator": map[string]interface{}{
+		"$ref": "#/definitions/operator",
+	},
+	"requeue": map[string]interface{}{
+		"type": "boolean",
+	},
+	"onMissing": map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{"", MissingNull, MissingFalse, MissingError},
+		"description": "How a path which does not resolve is compared: as null (the default), as a failed comparison or as an error. Existence operators ignore it.",
+	},
+	"or": map[string]interface{}{
+		"anyOf":       []interface{}{map[string]interface{}{"$ref": "#/definitions/filter"}, map[string]interface{}{"type": "null"}},
+		"description": "Filter tested when this filter does not pass.",
+	},
+	"and": map[string]interface{}{
+		"anyOf":       []interface{}{map[string]interface{}{"$ref": "#/definitions/filter"}, map[string]interface{}{"type": "null"}},
+		"description": "Filter which must also pass.",
+	},
+	"script": map[string]interface{}{
+		"anyOf": []interface{}{map[string]interface{}{"$ref": "#/definitions/script"}, map[string]interface{}{"type": "null"}},
+	},
+}
+
+var scriptFieldSchemas = map[string]interface{}{
+	"interpreter": map[string]interface{}{
+		"type": "string",
+		"enum": []string{"javascript", "js", "es5"},
+	},
+	"script": map[string]interface{}{
+		"type":        "string",
+		"description": "Script source. The message is available as input.",
+	},
+	"scriptFile": map[string]interface{}{
+		"type":        "string",
+		"description": "Path of a file containing the script, used instead of script.",
+	},
+	"metadata": map[string]interface{}{
+		"type":        []string{"object", "null"},
+		"description": "Available to the script as metadata.",
+	},
+}
+
+// JSONSchema generates the JSON Schema describing filter documents, including
+// every operator alias and the value shape each operator expects
+func JSONSchema() ([]byte, error) {
+	filterProps, err := schemaProperties(reflect.TypeOf(Filter{}), filterFieldSchemas)
+	if err != nil {
+		return nil, err
+	}
+	scriptProps, err := schemaProperties(reflect.TypeOf(ScriptFilter{}), scriptFieldSchemas)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	var conditions []interface{}
+	for _, op := range operators {
+		spellings := append([]string{op.Name}, op.Aliases...)
+		names = append(names, spellings...)
+		if op.Value == nil {
+			continue
+		}
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"properties": map[string]interface{}{"operator": map[string]interface{}{"enum": spellings}},
+				"required":   []string{"operator"},
+			},
+			"then": map[string]interface{}{
+				"properties": map[string]interface{}{"value": refSchema(op)},
+			},
+		})
+	}
+	schema := map[string]interface{}{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"$id":     SchemaID,
+		"title":   "Filter",
+		"$ref":    "#/definitions/filter",
+		"definitions": map[string]interface{}{
+			"filter": map[string]interface{}{
+				"type":                 "object",
+				"properties":           filterProps,
+				"additionalProperties": false,
+				"allOf":                conditions,
+			},
+			"script": map[string]interface{}{
+				"type":                 "object",
+				"properties":           scriptProps,
+				"additionalProperties": false,
+			},
+			"operator": map[string]interface{}{
+				"type": "string",
+				"enum": names,
+			},
+			"duration": map[string]interface{}{
+				"anyOf": []interface{}{
+					map[string]interface{}{"type": "string", "pattern": durationPattern},
+					map[string]interface{}{"$ref": "#/definitions/template"},
+				},
+			},
+			"template": map[string]interface{}{
+				"type":    "string",
+				"pattern": `\{\{`,
+			},
+			"elementCount": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"filter": map[string]interface{}{
+						"anyOf":       []interface{}{schemaFilter, map[string]interface{}{"type": "null"}},
+						"description": "Filter the counted elements pass, all elements when null.",
+					},
+					"operator": map[string]interface{}{
+						"type": "string",
+						"enum": countOperators(),
+					},
+					"value": map[string]interface{}{
+						"description": "Count to compare with, or a range for between.",
+					},
+				},
+				"required":             []string{"operator", "value"},
+				"additionalProperties": false,
+			},
+			"pathRef": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"pattern":     `^\$`,
+						"description": "JSONPath of the message value to compare against.",
+					},
+				},
+				"required":             []string{"path"},
+				"additionalProperties": false,
+			},
+			"type": map[string]interface{}{
+				"anyOf": []interface{}{
+					map[string]interface{}{"type": "string", "enum": valueTypes},
+					map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": valueTypes}},
+				},
+			},
+			"range": map[string]interface{}{
+				"anyOf": []interface{}{
+					map[string]interface{}{"type": "array", "minItems": 2, "maxItems": 2},
+					map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"min":          schemaBound,
+							"max":          schemaBound,
+							"minInclusive": map[string]interface{}{"type": "boolean", "default": true},
+							"maxInclusive": map[string]interface{}{"type": "boolean", "default": true},
+						},
+						"additionalProperties": false,
+					},
+				},
+			},
+		},
+	}
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// refSchema allows the value of op to refer to another path, unless it is an
+// element operator whose value is a nested filter
+func refSchema(op operator) interface{} {
+	if isElementOperator(op.Name) {
+		return op.Value
+	}
+	return map[string]interface{}{"anyOf": []interface{}{op.Value, map[string]interface{}{"$ref": "#/definitions/pathRef"}}}
+}
+
+// countOperators lists the spellings of the operators countElements supports
+func countOperators() []string {
+	var names []string
+	for _, op := range operators {
+		if isCountComparison(op.Name) {
+			names = append(names, op.Name)
+			names = append(names, op.Aliases...)
+		}
+	}
+	return names
+}
+
+// schemaProperties maps every json field of t to its schema, failing for
+// fields which have none so new fields can not be left out of the schema
+func schemaProperties(t reflect.Type, schemas map[string]interface{}) (map[string]interface{}, error) {
+	props := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema, ok := schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("no schema for %s field %s", t.Name(), name)
+		}
+		props[name] = schema
+	}
+	return props, nil
+}
@@ -0,0 +1,79 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateSchema = flag.Bool("update", false, "regenerate filter.schema.json")
+
+func TestJSONSchema(t *testing.T) {
+	generated, err := JSONSchema()
+	if err != nil {
+		t.Error("Failed to generate schema", err)
+		return
+	}
+	if *updateSchema {
+		err = os.WriteFile("filter.schema.json", generated, 0644)
+		if err != nil {
+			t.Error("Failed to write schema", err)
+		}
+		return
+	}
+	shipped, err := os.ReadFile("filter.schema.json")
+	if err != nil {
+		t.Error("Failed to read schema", err)
+		return
+	}
+	if !bytes.Equal(shipped, generated) {
+		t.Error("filter.schema.json is out of date, run go test -run TestJSONSchema -update")
+		return
+	}
+}
+
+func TestJSONSchemaOperators(t *testing.T) {
+	generated, err := JSONSchema()
+	if err != nil {
+		t.Error("Failed to generate schema", err)
+		return
+	}
+	var schema struct {
+		Definitions struct {
+			Operator struct {
+				Enum []string `json:"enum"`
+			} `json:"operator"`
+			Filter struct {
+				AllOf []interface{} `json:"allOf"`
+			} `json:"filter"`
+		} `json:"definitions"`
+	}
+	err = json.Unmarshal(generated, &schema)
+	if err != nil {
+		t.Error("Failed to parse schema", err)
+		return
+	}
+	var enum = map[string]bool{}
+	for _, name := range schema.Definitions.Operator.Enum {
+		enum[name] = true
+	}
+	for alias := range operatorAliases {
+		if !enum[alias] {
+			t.Errorf("operator %q missing from schema", alias)
+		}
+	}
+	if len(enum) != len(operatorAliases) {
+		t.Errorf("schema has %d operators, registry has %d", len(enum), len(operatorAliases))
+	}
+	var shaped int
+	for _, op := range operators {
+		if op.Value != nil {
+			shaped++
+		}
+	}
+	if len(schema.Definitions.Filter.AllOf) != shaped {
+		t.Errorf("schema constrains %d operator values, registry has %d", len(schema.Definitions.Filter.AllOf), shaped)
+	}
+}
@@ -0,0 +1,128 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/nickcarenza/go-template"
+)
+
+// testSet applies the set operator op to the message array val and the
+// filter list fVal. Elements are equal when eq finds them equal.
+func testSet(op string, msg interface{}, val interface{}, fVal interface{}) (bool, error) {
+	if val == nil {
+		return false, nil
+	}
+	elements, ok := val.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("TypeAssertionError")
+	}
+	list, ok := fVal.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("TypeAssertionError")
+	}
+	have, err := newValueSet(elements, nil)
+	if err != nil {
+		return false, err
+	}
+	want, err := newValueSet(list, msg)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case opContainsAll:
+		return subset(want, have), nil
+	case opContainsAny:
+		return !disjoint(want, have), nil
+	case opContainsNone:
+		return disjoint(want, have), nil
+	case opSubsetOf:
+		return subset(have, want), nil
+	case opEqualSet:
+		return subset(want, have) && subset(have, want), nil
+	default:
+		return false, fmt.Errorf("impossible condition")
+	}
+}
+
+// valueSet holds comparable values in a map, and objects and arrays, which
+// == can not compare, in a list searched with equalValues
+type valueSet struct {
+	values map[interface{}]bool
+	other  []interface{}
+}
+
+// newValueSet returns the set of values in list with numbers converted to
+// float64. Strings are interpolated against msg unless msg is nil.
+func newValueSet(list []interface{}, msg interface{}) (valueSet, error) {
+	set := valueSet{values: make(map[interface{}]bool, len(list))}
+	for _, v := range list {
+		switch e := v.(type) {
+		case json.Number:
+			n, err := e.Float64()
+			if err != nil {
+				return set, fmt.Errorf("TypeAssertionError")
+			}
+			v = n
+		case int:
+			v = float64(e)
+		case int64:
+			v = float64(e)
+		case string:
+			if msg != nil && isTemplate(e) {
+				data, err := messageValue(msg)
+				if err != nil {
+					return set, err
+				}
+				v, err = template.Interpolate(data, e)
+				if err != nil {
+					return set, err
+				}
+			}
+		}
+		if v != nil && !reflect.TypeOf(v).Comparable() {
+			set.other = append(set.other, v)
+			continue
+		}
+		set.values[v] = true
+	}
+	return set, nil
+}
+
+func (s valueSet) has(v interface{}) bool {
+	if v == nil || reflect.TypeOf(v).Comparable() {
+		return s.values[v]
+	}
+	for _, o := range s.other {
+		if equalValues(o, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// each calls fn with the values of s until it returns false
+func (s valueSet) each(fn func(v interface{}) bool) bool {
+	for v := range s.values {
+		if !fn(v) {
+			return false
+		}
+	}
+	for _, v := range s.other {
+		if !fn(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func subset(a, b valueSet) bool {
+	return a.each(b.has)
+}
+
+func disjoint(a, b valueSet) bool {
+	return a.each(func(v interface{}) bool {
+		return !b.has(v)
+	})
+}
@@ -0,0 +1,43 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestSetOperators(t *testing.T) {
+	msg, err := decodeJSONMessage([]byte(`{"tags":["vip","beta","eu"],"ids":[1,2,2],"segment":"beta","nested":[{"a":1}],"name":"x"}`))
+	if err != nil {
+		t.Error("Failed to parse message", err)
+		return
+	}
+	testFilterCases(t, msg, []filterCase{
+		{`{"path":"$.tags","operator":"containsAll","value":["vip","eu"]}`, true},
+		{`{"path":"$.tags","operator":"containsAll","value":["vip","us"]}`, false},
+		{`{"path":"$.tags","operator":"containsAll","value":[]}`, true},
+		{`{"path":"$.tags","operator":"containsAny","value":["us","eu"]}`, true},
+		{`{"path":"$.tags","operator":"intersects","value":["us","ca"]}`, false},
+		{`{"path":"$.tags","operator":"containsNone","value":["banned","test"]}`, true},
+		{`{"path":"$.tags","operator":"contains none","value":["beta"]}`, false},
+		{`{"path":"$.tags","operator":"subsetOf","value":["vip","beta","eu","us"]}`, true},
+		{`{"path":"$.tags","operator":"subsetOf","value":["vip","beta"]}`, false},
+		{`{"path":"$.tags","operator":"equalSet","value":["eu","vip","beta","eu"]}`, true},
+		{`{"path":"$.tags","operator":"equalSet","value":["eu","vip"]}`, false},
+		{`{"path":"$.ids","operator":"equalSet","value":[2,1]}`, true},
+		{`{"path":"$.nested","operator":"subsetOf","value":["x"]}`, false},
+		{`{"path":"$.nested","operator":"equalSet","value":[]}`, false},
+		{`{"path":"$.nested","operator":"equalSet","value":[{"a":1}]}`, true},
+		{`{"path":"$.nested","operator":"subsetOf","value":["x",{"a":1.0}]}`, true},
+		{`{"path":"$.nested","operator":"containsAll","value":[{"a":1},{"a":2}]}`, false},
+		{`{"path":"$.tags","operator":"containsAll","value":["vip",{"a":1}]}`, false},
+		{`{"path":"$.nested","operator":"containsNone","value":["x"]}`, true},
+		{`{"path":"$.nested","operator":"containsNone","value":[{"a":2}]}`, true},
+		{`{"path":"$.ids","operator":"containsAny","value":["1"]}`, false},
+		{`{"path":"$.tags","operator":"containsAll","value":["{{.segment}}"]}`, true},
+		{`{"path":"$.nested","operator":"containsAny","value":[{"a":1}]}`, true},
+		{`{"path":"$.missing","operator":"containsNone","value":["x"]}`, false},
+	})
+	testFilterErrors(t, msg, []string{
+		`{"path":"$.name","operator":"containsAll","value":["x"]}`,
+		`{"path":"$.tags","operator":"containsAll","value":"vip"}`,
+	})
+}
@@ -0,0 +1,285 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// StreamOptions configures Stream. The zero value evaluates one record at a
+// time, discards rejected records and stops at the first record error.
+type StreamOptions struct {
+	// Rejects receives the records which do not pass the filter
+	Rejects io.Writer
+	// Errors receives a JSON line for every record which is not valid JSON
+	// or fails to evaluate. When nil, Stream stops at the first such record.
+	Errors io.Writer
+	// Workers is the number of records evaluated concurrently. Records are
+	// written in input order regardless.
+	Workers int
+	// Explain receives the evaluation Trace of every record, preceded by a
+	// line with its position in the input
+	Explain io.Writer
+}
+
+// StreamResult counts the records read by Stream
+type StreamResult struct {
+	Records  int `json:"records"`
+	Matched  int `json:"matched"`
+	Rejected int `json:"rejected"`
+	Errors   int `json:"errors"`
+}
+
+// streamError is written to StreamOptions.Errors for every failed record.
+// Record is the 1-based position of the record in the input.
+type streamError struct {
+	Record int    `json:"record"`
+	Error  string `json:"error"`
+	Data   string `json:"data"`
+}
+
+type streamRecord struct {
+	n     int
+	data  []byte
+	pass  bool
+	err   error
+	trace *Trace
+	done  chan struct{}
+}
+
+// Stream reads JSON Lines from r and writes the records which pass f to w.
+// Input which is a single JSON array is read as its elements instead, unless
+// its first line is a whole array followed by more lines. Records are
+// written unchanged, one per line, and blank lines are skipped. Records are
+// evaluated with TestJSON after checking they are valid JSON.
+func Stream(r io.Reader, w io.Writer, f *Filter, opts StreamOptions) (StreamResult, error) {
+	var res StreamResult
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	records := make(chan *streamRecord, workers)
+	ordered := make(chan *streamRecord, workers*2)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	var readErr error
+	go func() {
+		defer close(ordered)
+		defer close(records)
+		n := 0
+		readErr = readRecords(r, func(data []byte) bool {
+			n++
+			rec := &streamRecord{n: n, data: data, done: make(chan struct{})}
+			select {
+			case ordered <- rec:
+			case <-stop:
+				return false
+			}
+			records <- rec
+			return true
+		})
+	}()
+	for i := 0; i < workers; i++ {
+		go func() {
+			for rec := range records {
+				rec.pass, rec.trace, rec.err = testRecord(f, rec.data, opts.Explain != nil)
+				close(rec.done)
+			}
+		}()
+	}
+
+	out := bufio.NewWriter(w)
+	var rejects, errs, explain *bufio.Writer
+	if opts.Rejects != nil {
+		rejects = bufio.NewWriter(opts.Rejects)
+	}
+	if opts.Errors != nil {
+		errs = bufio.NewWriter(opts.Errors)
+	}
+	if opts.Explain != nil {
+		explain = bufio.NewWriter(opts.Explain)
+	}
+	flush := func() error {
+		for _, b := range []*bufio.Writer{out, rejects, errs, explain} {
+			if b == nil {
+				continue
+			}
+			err := b.Flush()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for rec := range ordered {
+		<-rec.done
+		res.Records++
+		var err error
+		if explain != nil && rec.trace != nil {
+			_, err = fmt.Fprintf(explain, "record %d\n%s", rec.n, rec.trace)
+			if err != nil {
+				return res, err
+			}
+		}
+		switch {
+		case rec.err != nil:
+			res.Errors++
+			if errs == nil {
+				flush()
+				return res, fmt.Errorf("record %d: %w", rec.n, rec.err)
+			}
+			var line []byte
+			line, err = json.Marshal(streamError{rec.n, rec.err.Error(), string(bytes.TrimSpace(rec.data))})
+			if err == nil {
+				err = writeLine(errs, line)
+			}
+		case rec.pass:
+			res.Matched++
+			err = writeLine(out, rec.data)
+		default:
+			res.Rejected++
+			if rejects != nil {
+				err = writeLine(rejects, rec.data)
+			}
+		}
+		if err != nil {
+			return res, err
+		}
+		// flush while waiting on input so output keeps up with slow streams
+		if len(ordered) == 0 {
+			err = flush()
+			if err != nil {
+				return res, err
+			}
+		}
+	}
+	err := flush()
+	if err != nil {
+		return res, err
+	}
+	return res, readErr
+}
+
+// testRecord evaluates f against one encoded record, tracing the evaluation
+// when explain is set
+func testRecord(f *Filter, data []byte, explain bool) (bool, *Trace, error) {
+	data = bytes.TrimSpace(data)
+	if !json.Valid(data) {
+		var raw json.RawMessage
+		err := json.Unmarshal(data, &raw)
+		if err == nil {
+			err = fmt.Errorf("invalid JSON")
+		}
+		return false, nil, err
+	}
+	if explain {
+		t, err := f.Explain(&RawJSON{Data: data})
+		return t.Result, t, err
+	}
+	pass, err := f.TestJSON(data)
+	return pass, nil, err
+}
+
+// writeLine writes data followed by a newline unless it already ends in one
+func writeLine(w *bufio.Writer, data []byte) error {
+	_, err := w.Write(data)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || data[len(data)-1] != '\n' {
+		return w.WriteByte('\n')
+	}
+	return nil
+}
+
+// readRecords calls emit with every record in r until emit returns false
+func readRecords(r io.Reader, emit func([]byte) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+			continue
+		case '[':
+			first, err := br.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			lines := json.Valid(bytes.TrimSpace(first)) && moreData(br)
+			br = bufio.NewReader(io.MultiReader(bytes.NewReader(first), br))
+			if lines {
+				// JSON Lines whose records are arrays
+				return readLines(br, emit)
+			}
+			return readArray(br, emit)
+		}
+		return readLines(br, emit)
+	}
+}
+
+// moreData skips white space and reports whether anything follows it
+func moreData(br *bufio.Reader) bool {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+			continue
+		}
+		return true
+	}
+}
+
+func readLines(br *bufio.Reader, emit func([]byte) bool) error {
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 && !emit(line) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func readArray(br *bufio.Reader, emit func([]byte) bool) error {
+	dec := json.NewDecoder(br)
+	_, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		err = dec.Decode(&raw)
+		if err != nil {
+			return err
+		}
+		if !emit(raw) {
+			return nil
+		}
+	}
+	_, err = dec.Token()
+	if err != nil {
+		return err
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after JSON array")
+	}
+	return nil
+}
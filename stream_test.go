@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.n","operator":">","value":2}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	input := "{\"n\":1}\n{ \"n\" : 3 }\r\n\n{\"n\":\n{\"n\":4}"
+	var out, rejects, errs bytes.Buffer
+	res, err := Stream(strings.NewReader(input), &out, filter, StreamOptions{Rejects: &rejects, Errors: &errs})
+	if err != nil {
+		t.Error("Stream failed", err)
+		return
+	}
+	if res != (StreamResult{Records: 4, Matched: 2, Rejected: 1, Errors: 1}) {
+		t.Errorf("Unexpected counts %+v", res)
+	}
+	if out.String() != "{ \"n\" : 3 }\r\n{\"n\":4}\n" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+	if rejects.String() != "{\"n\":1}\n" {
+		t.Errorf("Unexpected rejects %q", rejects.String())
+	}
+	if !strings.HasPrefix(errs.String(), `{"record":3,"error":`) || !strings.HasSuffix(errs.String(), `"data":"{\"n\":"}`+"\n") {
+		t.Errorf("Unexpected errors %q", errs.String())
+	}
+}
+
+func TestStreamArray(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.n","operator":"in","value":[1,3]}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var out bytes.Buffer
+	res, err := Stream(strings.NewReader(` [{"n":1}, {"n":2},{ "n":3 }]`), &out, filter, StreamOptions{})
+	if err != nil {
+		t.Error("Stream failed", err)
+		return
+	}
+	if res.Records != 3 || res.Matched != 2 {
+		t.Errorf("Unexpected counts %+v", res)
+	}
+	if out.String() != "{\"n\":1}\n{ \"n\":3 }\n" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
+
+func TestStreamArrayLines(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$[1]","operator":">","value":2}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var out bytes.Buffer
+	res, err := Stream(strings.NewReader("[1,2]\n[1,3]\n[1,4]\n"), &out, filter, StreamOptions{})
+	if err != nil {
+		t.Error("Stream failed", err)
+		return
+	}
+	if res.Records != 3 || res.Matched != 2 {
+		t.Errorf("Unexpected counts %+v", res)
+	}
+	if out.String() != "[1,3]\n[1,4]\n" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+	_, err = Stream(strings.NewReader("[{\"n\":1},\n{\"n\":2}]\n{\"n\":3}\n"), &out, filter, StreamOptions{})
+	if err == nil {
+		t.Error("data after the array should fail")
+	}
+}
+
+func TestStreamWorkersPreserveOrder(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.n","operator":"regexMatch","value":"[02468]$"}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var input, expected strings.Builder
+	for i := 0; i < 1000; i++ {
+		line := fmt.Sprintf("{\"n\":\"%d\"}\n", i)
+		input.WriteString(line)
+		if i%2 == 0 {
+			expected.WriteString(line)
+		}
+	}
+	var out bytes.Buffer
+	res, err := Stream(strings.NewReader(input.String()), &out, filter, StreamOptions{Workers: 8})
+	if err != nil {
+		t.Error("Stream failed", err)
+		return
+	}
+	if res.Matched != 500 || res.Rejected != 500 {
+		t.Errorf("Unexpected counts %+v", res)
+	}
+	if out.String() != expected.String() {
+		t.Error("Records were reordered")
+	}
+}
+
+func TestStreamStopsOnError(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.n","value":1}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	var out bytes.Buffer
+	res, err := Stream(strings.NewReader("{\"n\":1}\nnope\n{\"n\":1}\n"), &out, filter, StreamOptions{Workers: 2})
+	if err == nil || !strings.HasPrefix(err.Error(), "record 2:") {
+		t.Error("Expected record error, got", err)
+	}
+	if res.Matched != 1 || out.String() != "{\"n\":1}\n" {
+		t.Errorf("Unexpected result %+v %q", res, out.String())
+	}
+}
@@ -0,0 +1,74 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testString applies the string operator op to the message value s and the
+// filter value sub
+func testString(op string, s string, sub string) bool {
+	switch op {
+	case opContainsIgnoreCase, opNotContainsIgnoreCase, opStartsWithIgnoreCase, opEndsWithIgnoreCase:
+		s, sub = caseFold(s), caseFold(sub)
+	}
+	switch op {
+	case opContains, opContainsIgnoreCase:
+		return strings.Contains(s, sub)
+	case opNotContains, opNotContainsIgnoreCase:
+		return !strings.Contains(s, sub)
+	case opStartsWith, opStartsWithIgnoreCase:
+		return strings.HasPrefix(s, sub)
+	case opEndsWith, opEndsWithIgnoreCase:
+		return strings.HasSuffix(s, sub)
+	case opEqualsIgnoreCase:
+		return strings.EqualFold(s, sub)
+	default:
+		return false
+	}
+}
+
+// isStringOperator reports whether the canonical operator op compares strings
+func isStringOperator(op string) bool {
+	switch op {
+	case opContains, opNotContains, opStartsWith, opEndsWith, opEqualsIgnoreCase,
+		opContainsIgnoreCase, opNotContainsIgnoreCase, opStartsWithIgnoreCase, opEndsWithIgnoreCase:
+		return true
+	}
+	return false
+}
+
+// caseFold maps every rune of s to the smallest rune it is equal to under
+// simple Unicode case folding, the folding strings.EqualFold uses. Unlike
+// ToLower it folds every case variant, such as the Kelvin sign and k, to the
+// same rune.
+func caseFold(s string) string {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf || 'a' <= c && c <= 'z' {
+			return caseFoldSlow(s, i)
+		}
+	}
+	return s
+}
+
+func caseFoldSlow(s string, i int) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	b.WriteString(s[:i])
+	for _, r := range s[i:] {
+		b.WriteRune(foldRune(r))
+	}
+	return b.String()
+}
+
+func foldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
@@ -0,0 +1,55 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestStringOperators(t *testing.T) {
+	msg := map[string]interface{}{
+		"message": "GET /api/orders failed: Timeout",
+		"host":    "API-1.example.com",
+		"city":    "İstanbul",
+		"unit":    "50 K",
+		"code":    503.0,
+		"region":  "eu",
+	}
+	testFilterCases(t, msg, []filterCase{
+		{`{"path":"$.message","operator":"contains","value":"failed"}`, true},
+		{`{"path":"$.message","operator":"contains","value":"timeout"}`, false},
+		{`{"path":"$.message","operator":"icontains","value":"timeout"}`, true},
+		{`{"path":"$.message","operator":"not contains","value":"failed"}`, false},
+		{`{"path":"$.message","operator":"notContainsIgnoreCase","value":"TIMEOUT"}`, false},
+		{`{"path":"$.message","operator":"startsWith","value":"GET "}`, true},
+		{`{"path":"$.host","operator":"starts with","value":"api-"}`, false},
+		{`{"path":"$.host","operator":"istartsWith","value":"api-"}`, true},
+		{`{"path":"$.host","operator":"endsWith","value":".example.com"}`, true},
+		{`{"path":"$.host","operator":"endsWithIgnoreCase","value":"EXAMPLE.COM"}`, true},
+		{`{"path":"$.host","operator":"equalsIgnoreCase","value":"api-1.EXAMPLE.com"}`, true},
+		{`{"path":"$.unit","operator":"endsWithIgnoreCase","value":"k"}`, true},
+		{`{"path":"$.city","operator":"containsIgnoreCase","value":"STANBUL"}`, true},
+		{`{"path":"$.host","operator":"contains","value":"{{.region}}"}`, false},
+		{`{"path":"$.host","operator":"icontains","value":"{{.region}}"}`, false},
+		{`{"path":"$.message","operator":"icontains","value":"{{.region}}"}`, false},
+		{`{"path":"$.host","operator":"istartsWith","value":"{{ printf \"%s-\" \"api\" }}"}`, true},
+		{`{"path":"$.missing","operator":"contains","value":"x"}`, false},
+	})
+	testFilterErrors(t, msg, []string{
+		`{"path":"$.code","operator":"contains","value":"50"}`,
+	})
+}
+
+func TestCaseFold(t *testing.T) {
+	for _, c := range [][2]string{
+		{"abc", "ABC"},
+		{"Straße", "STRAßE"},
+		{"K", "k"},
+		{"Σίσυφος", "ΣΊΣΥΦΟΣ"},
+	} {
+		if caseFold(c[0]) != caseFold(c[1]) {
+			t.Errorf("%s and %s should fold equal", c[0], c[1])
+		}
+	}
+	if caseFold("ab1") != "AB1" {
+		t.Errorf("Unexpected fold %s", caseFold("ab1"))
+	}
+}
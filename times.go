@@ -0,0 +1,55 @@
+package filter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/the-control-group/go-timeutils"
+)
+
+// testTime applies the time operator op to the timestamp val. fVal is a
+// timestamp for before and after and a duration for withinLast and withinNext.
+func testTime(op string, val interface{}, fVal interface{}) (bool, error) {
+	if val == nil {
+		return false, nil
+	}
+	tStr, ok := val.(string)
+	if !ok {
+		return false, fmt.Errorf("TypeAssertionError")
+	}
+	tVal, err := timeutils.ParseAny(tStr)
+	if err != nil {
+		return false, err
+	}
+	rVal, ok := fVal.(string)
+	if !ok {
+		return false, fmt.Errorf("TypeAssertionError")
+	}
+	switch op {
+	case opBefore, opAfter:
+		ref, err := timeutils.ParseAny(rVal)
+		if err != nil {
+			return false, err
+		}
+		if op == opBefore {
+			return tVal.Before(ref), nil
+		}
+		return tVal.After(ref), nil
+	case opWithinLast, opWithinNext:
+		// ParseApproxBigDuration reads what it does not understand as 0
+		if !durationRegexp.MatchString(rVal) {
+			return false, fmt.Errorf("invalid duration %q", rVal)
+		}
+		dVal, err := timeutils.ParseApproxBigDuration([]byte(rVal))
+		if err != nil {
+			return false, err
+		}
+		since := time.Since(tVal)
+		if op == opWithinLast {
+			return since >= 0 && since <= time.Duration(dVal), nil
+		}
+		return since <= 0 && -since <= time.Duration(dVal), nil
+	default:
+		return false, fmt.Errorf("impossible condition")
+	}
+}
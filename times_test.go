@@ -0,0 +1,47 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeOperators(t *testing.T) {
+	now := time.Now()
+	msg := map[string]interface{}{
+		"created": now.Add(-2 * time.Hour).Format(time.RFC3339),
+		"expires": now.Add(48 * time.Hour).Format(time.RFC3339),
+		"start":   "2024-03-01T00:00:00Z",
+		"end":     "2024-04-01T00:00:00Z",
+		"date":    "2024-03-15",
+		"name":    "alice",
+		"count":   3.0,
+	}
+	testFilterCases(t, msg, []filterCase{
+		{`{"path":"$.date","operator":"after","value":"2024-03-01T00:00:00Z"}`, true},
+		{`{"path":"$.date","operator":"before","value":"2024-03-01"}`, false},
+		{`{"path":"$.date","operator":"is before","value":"{{ .end }}"}`, true},
+		{`{"path":"$.date","operator":"after","value":{"path":"$.start"}}`, true},
+		{`{"path":"$.end","operator":"before","value":{"path":"$.start"}}`, false},
+		{`{"path":"$.start","operator":"after","value":{"path":"$.start"}}`, false},
+		{`{"path":"$.date","operator":"between","value":[{"path":"$.start"},"2024-04-01"]}`, true},
+		{`{"path":"$.date","operator":"notBetween","value":{"min":{"path":"$.start"},"max":{"path":"$.end"}}}`, false},
+		{`{"path":"$.date","operator":"between","value":{"min":{"path":"$.missing"},"max":{"path":"$.end"}},"onMissing":"false"}`, false},
+		{`{"path":"$.date","operator":"between","value":[{"path":"$.missing"},{"path":"$.other"}]}`, false},
+		{`{"path":"$.date","operator":"notBetween","value":[{"path":"$.start"},{"path":"$.missing"}]}`, false},
+		{`{"path":"$.date","operator":"between","value":["2024-03-01","2024-04-01T00:00:00Z"]}`, true},
+		{`{"path":"$.created","operator":"withinLast","value":"3h"}`, true},
+		{`{"path":"$.created","operator":"within last","value":"1h"}`, false},
+		{`{"path":"$.expires","operator":"withinLast","value":"7d"}`, false},
+		{`{"path":"$.expires","operator":"withinNext","value":"7d"}`, true},
+		{`{"path":"$.expires","operator":"within the next","value":"1d"}`, false},
+		{`{"path":"$.created","operator":"withinNext","value":"7d"}`, false},
+		{`{"path":"$.missing","operator":"before","value":"2024-03-01"}`, false},
+	})
+	testFilterErrors(t, msg, []string{
+		`{"path":"$.name","operator":"before","value":"2024-03-01"}`,
+		`{"path":"$.date","operator":"after","value":"soon"}`,
+		`{"path":"$.count","operator":"withinLast","value":"1d"}`,
+		`{"path":"$.created","operator":"withinLast","value":"a week"}`,
+		`{"path":"$.expires","operator":"withinNext","value":"{{ .name }}"}`,
+	})
+}
@@ -0,0 +1,146 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nickcarenza/go-template"
+)
+
+// Trace records how a filter evaluated against a message. Only the nodes Test
+// evaluates are recorded: Or is skipped when the node passes and And when it
+// fails.
+type Trace struct {
+	Path     string `json:"path,omitempty"`
+	Template string `json:"template,omitempty"`
+	Script   string `json:"script,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	// Expected is the filter value after interpolation
+	Expected interface{} `json:"expected,omitempty"`
+	// Actual is the value resolved from the message
+	Actual interface{} `json:"actual,omitempty"`
+	// Missing is set when the path did not resolve and the node was decided
+	// by the existence operator or OnMissing without comparing
+	Missing bool `json:"missing,omitempty"`
+	// Pass is the result of this node alone
+	Pass bool `json:"pass"`
+	// Result is the result of this node combined with Or and And
+	Result bool   `json:"result"`
+	Error  string `json:"error,omitempty"`
+	Or     *Trace `json:"or,omitempty"`
+	And    *Trace `json:"and,omitempty"`
+}
+
+// Explain evaluates the filter like Test, recording the operands and result
+// of every node it evaluates
+func (f *Filter) Explain(msg interface{}) (*Trace, error) {
+	t := &Trace{Operator: f.Operator}
+	if op, ok := canonicalOperator(f.Operator); ok {
+		t.Operator = op
+	}
+	var err error
+	switch {
+	case f.Script != nil:
+		t.Script = f.Script.Interpreter
+		t.Operator = ""
+		t.Pass, err = testScript(f, msg)
+	default:
+		if f.Template != nil {
+			t.Template = templateSource(f.Template)
+		} else {
+			t.Path = f.Path.String()
+		}
+		var val, fVal interface{}
+		val, fVal, err = operands(f, msg)
+		if err == errMissingPath {
+			t.Missing, t.Expected = true, f.Value
+			t.Pass, err = testMissing(f), nil
+		} else if err == nil {
+			t.Actual, t.Expected = val, fVal
+			t.Pass, err = testOperator(f, msg, val, fVal)
+		}
+	}
+	if err != nil {
+		t.Error = err.Error()
+		return t, err
+	}
+	t.Result = t.Pass
+	if !t.Result && f.Or != nil {
+		t.Or, err = f.Or.Explain(msg)
+		if err != nil {
+			return t, err
+		}
+		t.Result = t.Or.Result
+	}
+	if t.Result && f.And != nil {
+		t.And, err = f.And.Explain(msg)
+		if err != nil {
+			return t, err
+		}
+		t.Result = t.And.Result
+	}
+	return t, nil
+}
+
+// String renders the trace one node per line
+func (t *Trace) String() string {
+	var b strings.Builder
+	t.write(&b, "", "")
+	return b.String()
+}
+
+func (t *Trace) write(b *strings.Builder, indent string, label string) {
+	status := "FAIL"
+	if t.Error != "" {
+		status = "ERROR"
+	} else if t.Pass {
+		status = "PASS"
+	}
+	fmt.Fprintf(b, "%s%s%s ", indent, label, status)
+	switch {
+	case t.Script != "":
+		fmt.Fprintf(b, "script %s", t.Script)
+	default:
+		subject := t.Path
+		if t.Template != "" {
+			subject = traceValue(t.Template)
+		}
+		fmt.Fprintf(b, "%s %s", subject, t.Operator)
+		if !unaryOperator(t.Operator) {
+			fmt.Fprintf(b, " %s", traceValue(t.Expected))
+		}
+		switch {
+		case t.Missing:
+			b.WriteString(" (missing)")
+		case t.Error == "":
+			fmt.Fprintf(b, " (actual %s)", traceValue(t.Actual))
+		}
+	}
+	if t.Error != "" {
+		fmt.Fprintf(b, ": %s", t.Error)
+	}
+	b.WriteByte('\n')
+	if t.Or != nil {
+		t.Or.write(b, indent, "or ")
+	}
+	if t.And != nil {
+		t.And.write(b, indent+"  ", "and ")
+	}
+}
+
+// templateSource returns the source text of t
+func templateSource(t *template.Template) string {
+	var src string
+	b, _ := json.Marshal(t)
+	json.Unmarshal(b, &src)
+	return src
+}
+
+func traceValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
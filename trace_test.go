@@ -0,0 +1,72 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	f, err := ParseJSON([]byte(`{"path":"$.amount","operator":">","value":100,"or":{"path":"$.vip","value":true},"and":{"path":"$.country","operator":"in","value":["US","CA"]}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	msg := map[string]interface{}{"amount": 50.0, "vip": true, "country": "MX"}
+	trace, err := f.Explain(msg)
+	if err != nil {
+		t.Error("Explain failed", err)
+		return
+	}
+	pass, err := f.Test(msg)
+	if err != nil {
+		t.Error("Filter test failed", err)
+		return
+	}
+	if trace.Result != pass {
+		t.Error("Explain and Test disagree")
+	}
+	expected := `FAIL $.amount gt 100 (actual 50)
+or PASS $.vip eq true (actual true)
+  and FAIL $.country in ["US","CA"] (actual "MX")
+`
+	if trace.String() != expected {
+		t.Errorf("Unexpected trace\n%s", trace)
+	}
+}
+
+func TestExplainError(t *testing.T) {
+	f, err := ParseJSON([]byte(`{"path":"$.created","operator":"olderThan","value":"1h"}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	trace, err := f.Explain(map[string]interface{}{"created": 5.0})
+	if err == nil {
+		t.Error("Explain should fail")
+		return
+	}
+	if trace.Error != err.Error() {
+		t.Error("Trace should record the error")
+	}
+	if trace.String() != "ERROR $.created olderThan \"1h\": TypeAssertionError\n" {
+		t.Errorf("Unexpected trace %q", trace)
+	}
+}
+
+func TestExplainMissing(t *testing.T) {
+	f, err := ParseJSON([]byte(`{"path":"$.deleted","operator":"exists","or":{"path":"$.amount","operator":">","value":1,"onMissing":"false"}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	trace, err := f.Explain(map[string]interface{}{})
+	if err != nil {
+		t.Error("Explain failed", err)
+		return
+	}
+	expected := `FAIL $.deleted exists (missing)
+or FAIL $.amount gt 1 (missing)
+`
+	if trace.String() != expected {
+		t.Errorf("Unexpected trace\n%s", trace)
+	}
+}
@@ -0,0 +1,136 @@
+package filter
+
+import (
+	"reflect"
+
+	"github.com/the-control-group/go-jsonpath"
+)
+
+// Typed evaluates a filter directly against Go values of type T. Paths are
+// resolved through the json tags of T when the Typed is created, so each test
+// only follows precomputed field indexes instead of encoding the value.
+type Typed[T any] struct {
+	Filter    *Filter
+	accessors map[string]*typedAccessor
+}
+
+// typedAccessor resolves one path: fields are the struct steps known from T,
+// rest are the segments which depend on the runtime value (slices, maps,
+// interfaces)
+type typedAccessor struct {
+	fields []structField
+	rest   []pathSegment
+}
+
+// NewTyped prepares f for evaluation against values of type T
+func NewTyped[T any](f *Filter) *Typed[T] {
+	t := &Typed[T]{Filter: f, accessors: map[string]*typedAccessor{}}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	compile := func(path string) {
+		if _, ok := t.accessors[path]; ok {
+			return
+		}
+		if segments, ok := cachedPath(path); ok {
+			t.accessors[path] = compileAccessor(typ, segments)
+		}
+	}
+	walkFilters(f, func(node *Filter) {
+		if ref, ok := node.Value.(*PathRef); ok {
+			compile(ref.Path.String())
+		}
+		if node.Template != nil || node.Script != nil {
+			return
+		}
+		compile(node.Path.String())
+	})
+	return t
+}
+
+// Test evaluates the filter against v
+func (t *Typed[T]) Test(v T) (bool, error) {
+	return t.Filter.Test(typedResolver{reflectResolver{v}, t.accessors})
+}
+
+type typedResolver struct {
+	reflectResolver
+	accessors map[string]*typedAccessor
+}
+
+// Resolve implements Resolver
+func (r typedResolver) Resolve(path jsonpath.JsonPath) (interface{}, error) {
+	a, ok := r.accessors[path.String()]
+	if !ok {
+		return r.reflectResolver.Resolve(path)
+	}
+	v := reflect.ValueOf(r.msg)
+	for _, field := range a.fields {
+		v = reflectIndirect(v)
+		if !v.IsValid() {
+			return nil, notFound("unknown key %s", field.Name)
+		}
+		fv, ok := fieldByIndex(v, field.Index)
+		if !ok || (field.OmitEmpty && isEmptyValue(fv)) {
+			return nil, notFound("unknown key %s", field.Name)
+		}
+		v = fv
+	}
+	for _, s := range a.rest {
+		var err error
+		v, err = reflectSegment(v, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reflectValue(v)
+}
+
+// compileAccessor resolves the leading struct fields of segments against t
+func compileAccessor(t reflect.Type, segments []pathSegment) *typedAccessor {
+	a := &typedAccessor{}
+	for i, s := range segments {
+		for t.Kind() == reflect.Pointer && !typeMarshaler(t) {
+			t = t.Elem()
+		}
+		field, ok := typeField(t, s)
+		if !ok {
+			a.rest = segments[i:]
+			return a
+		}
+		a.fields = append(a.fields, field)
+		t = t.FieldByIndex(field.Index).Type
+	}
+	return a
+}
+
+// typeField finds the struct field s selects in t, if t is a plain struct
+func typeField(t reflect.Type, s pathSegment) (structField, bool) {
+	if t.Kind() != reflect.Struct || s.IsIndex || typeMarshaler(t) {
+		return structField{}, false
+	}
+	for _, field := range cachedFields(t) {
+		if field.Name == s.Key {
+			return field, true
+		}
+	}
+	return structField{}, false
+}
+
+// typeMarshaler reports whether values of t or *t marshal themselves to JSON
+func typeMarshaler(t reflect.Type) bool {
+	for _, t := range []reflect.Type{t, reflect.PointerTo(t)} {
+		if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkFilters calls fn for f and every filter nested in its Or and And clauses
+func walkFilters(f *Filter, fn func(*Filter)) {
+	if f == nil {
+		return
+	}
+	fn(f)
+	walkFilters(f.Or, fn)
+	walkFilters(f.And, fn)
+}
@@ -0,0 +1,50 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	filter, err := ParseJSON([]byte(`{"path":"$.address.country","operator":"in","value":["US","CA"],"and":{"path":"$.tags[0]","value":"a","or":{"path":"$.id","operator":">=","value":10}}}`))
+	if err != nil {
+		t.Error("Failed to parse filter", err)
+		return
+	}
+	typed := NewTyped[*testOrder](filter)
+	if len(typed.accessors) != 3 {
+		t.Errorf("expected 3 accessors, got %d", len(typed.accessors))
+		return
+	}
+	cases := []struct {
+		order testOrder
+		pass  bool
+	}{
+		{testOrder{Address: &testAddress{Country: "US"}, Tags: []string{"a"}}, true},
+		{testOrder{Address: &testAddress{Country: "US"}, Tags: []string{"b"}}, false},
+		{testOrder{testBase: testBase{ID: 10}, Address: &testAddress{Country: "CA"}}, true},
+		{testOrder{testBase: testBase{ID: 10}}, false},
+	}
+	for i, c := range cases {
+		pass, err := typed.Test(&c.order)
+		if err != nil {
+			t.Errorf("case %d: Filter test failed %s", i, err)
+			continue
+		}
+		if pass != c.pass {
+			t.Errorf("case %d should pass: %t", i, c.pass)
+		}
+	}
+}
+
+func BenchmarkTyped(b *testing.B) {
+	filter, err := ParseJSON([]byte(`{"path":"$.address.country","operator":"in","value":["US","CA"]}`))
+	if err != nil {
+		b.Error("Failed to parse filter", err)
+		return
+	}
+	typed := NewTyped[testOrder](filter)
+	order := testOrder{Address: &testAddress{Country: "CA"}}
+	for i := 0; i < b.N; i++ {
+		typed.Test(order)
+	}
+}
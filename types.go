@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/the-control-group/go-timeutils"
+)
+
+// valueTypes are the type names isType accepts
+var valueTypes = []string{"string", "number", "integer", "boolean", "array", "object", "null"}
+
+// typeNames reads the value of isType, a type name or a list of them
+func typeNames(v interface{}) ([]string, error) {
+	var names []string
+	switch v := v.(type) {
+	case string:
+		names = []string{v}
+	case []interface{}:
+		for _, n := range v {
+			s, ok := n.(string)
+			if !ok {
+				return nil, fmt.Errorf("TypeAssertionError")
+			}
+			names = append(names, s)
+		}
+	default:
+		return nil, fmt.Errorf("TypeAssertionError")
+	}
+	for _, n := range names {
+		if !isValueType(n) {
+			return nil, fmt.Errorf("unknown type %q, expected one of %v", n, valueTypes)
+		}
+	}
+	return names, nil
+}
+
+func isValueType(name string) bool {
+	for _, t := range valueTypes {
+		if name == t {
+			return true
+		}
+	}
+	return false
+}
+
+// testType applies the type operator op to the message value val
+func testType(op string, val interface{}, fVal interface{}) (bool, error) {
+	switch op {
+	case opIsNumeric:
+		_, err := interfaceToFloat64(val)
+		return err == nil, nil
+	case opIsTimestamp:
+		s, ok := val.(string)
+		if !ok {
+			return false, nil
+		}
+		_, err := timeutils.ParseAny(s)
+		return err == nil, nil
+	}
+	names, err := typeNames(fVal)
+	if err != nil {
+		return false, err
+	}
+	kind := valueKind(val)
+	for _, n := range names {
+		if n == kind || n == "integer" && kind == "number" && isInteger(val) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isInteger reports whether the number v has no fractional part
+func isInteger(v interface{}) bool {
+	f, err := interfaceToFloat64(v)
+	return err == nil && !math.IsInf(f, 0) && f == math.Trunc(f)
+}
@@ -0,0 +1,36 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestTypeOperators(t *testing.T) {
+	msg, err := decodeJSONMessage([]byte(`{"s":"abc","n":1.5,"i":3,"b":true,"a":[1],"o":{},"z":null,"ns":"42.5","ts":"2024-03-15T10:00:00Z"}`))
+	if err != nil {
+		t.Error("Failed to parse message", err)
+		return
+	}
+	testFilterCases(t, msg, []filterCase{
+		{`{"path":"$.s","operator":"isType","value":"string"}`, true},
+		{`{"path":"$.n","operator":"isType","value":"number"}`, true},
+		{`{"path":"$.n","operator":"isType","value":"integer"}`, false},
+		{`{"path":"$.i","operator":"isType","value":"integer"}`, true},
+		{`{"path":"$.b","operator":"isType","value":"boolean"}`, true},
+		{`{"path":"$.a","operator":"isType","value":"array"}`, true},
+		{`{"path":"$.o","operator":"isType","value":"object"}`, true},
+		{`{"path":"$.z","operator":"isType","value":"null"}`, true},
+		{`{"path":"$.ns","operator":"isType","value":"number"}`, false},
+		{`{"path":"$.ns","operator":"isType","value":["number","string"]}`, true},
+		{`{"path":"$.missing","operator":"isType","value":"string"}`, false},
+		{`{"path":"$.ns","operator":"isNumeric"}`, true},
+		{`{"path":"$.i","operator":"isNumeric"}`, true},
+		{`{"path":"$.s","operator":"isNumeric"}`, false},
+		{`{"path":"$.b","operator":"isNumeric"}`, false},
+		{`{"path":"$.ts","operator":"isTimestamp"}`, true},
+		{`{"path":"$.s","operator":"isTimestamp"}`, false},
+		{`{"path":"$.n","operator":"isTimestamp"}`, false},
+	})
+	testFilterErrors(t, msg, []string{
+		`{"path":"$.s","operator":"isType","value":"text"}`,
+	})
+}